@@ -1,21 +1,97 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"roadmapapi/internal/app"
+	"roadmapapi/internal/config"
+	"roadmapapi/internal/logging"
 	"roadmapapi/internal/routes"
+	"roadmapapi/internal/server"
+	"roadmapapi/internal/tracing"
 )
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, flags, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-	r := routes.NewRouter()
-	log.Printf("Roadmap API running on :%s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
+	if flags.PrintConfig {
+		b, err := cfg.Redacted().JSON()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+		return
+	}
+
+	logger, level, err := logging.New(cfg.Log.Level, cfg.Log.Format, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// A second signal during shutdown falls through to the default
+		// handler and kills the process immediately.
+		<-ctx.Done()
+		stop()
+	}()
+
+	a, err := app.New(cfg, logger, level)
+	if err != nil {
+		logger.Error("startup failed", "error", err)
+		os.Exit(1)
+	}
+	srv, err := server.New(cfg.Server, routes.NewRouter(a), logger)
+	if err != nil {
+		logger.Error("server setup failed", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Tracing.Enabled {
+		tracer := tracing.NewTracer(newSpanExporter(cfg.Tracing), cfg.Tracing.SampleRatio)
+		tracing.SetDefault(tracer)
+		srv.OnShutdown("tracing", tracer.Shutdown)
+	}
+
+	// The app gets a context of its own: a signal must not cancel in-flight
+	// polls before Shutdown has waited for them.
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+	a.Start(appCtx)
+	srv.OnShutdown("app", a.Shutdown)
+	if err := srv.Run(ctx); err != nil {
+		logger.Error("server stopped with error", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("shutdown complete")
+}
+
+func newSpanExporter(cfg config.TracingConfig) tracing.Exporter {
+	if cfg.Exporter == "otlp" {
+		headers := make(map[string]string, len(cfg.Headers))
+		for _, h := range cfg.Headers {
+			k, v, _ := strings.Cut(h, "=")
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		return tracing.NewOTLPExporter(cfg.Endpoint, cfg.ServiceName, headers, 10*time.Second)
+	}
+	return tracing.NewStdoutExporter(os.Stdout, cfg.ServiceName)
 }
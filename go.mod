@@ -2,4 +2,8 @@ module roadmapapi
 
 go 1.24
 
-require github.com/go-chi/chi/v5 v5.2.2
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/go-chi/chi/v5 v5.2.2
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"roadmapapi/internal/auth"
+	"roadmapapi/internal/store"
+)
+
+const (
+	auditDoc     = "audit-log"
+	auditEntries = 1000
+)
+
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query,omitempty"`
+	Status    int       `json:"status"`
+	KeyID     string    `json:"keyId,omitempty"`
+	KeyName   string    `json:"keyName,omitempty"`
+	RemoteIP  string    `json:"remoteIp"`
+	RequestID string    `json:"requestId,omitempty"`
+}
+
+// Auditor records every admin request, reads included since they expose keys,
+// usage and configuration, to the log and to a bounded, persisted list.
+type Auditor struct {
+	store  *store.Store
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func NewAuditor(st *store.Store, logger *slog.Logger) (*Auditor, error) {
+	a := &Auditor{store: st, logger: logger.With("log", "audit")}
+	if _, err := st.Load(auditDoc, &a.entries); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Auditor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		e := AuditEntry{
+			Time:      time.Now().UTC(),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Query:     scrubQuery(r.URL.Query()),
+			Status:    status,
+			RemoteIP:  remoteIP(r),
+			RequestID: middleware.GetReqID(r.Context()),
+		}
+		if p := auth.FromContext(r.Context()); p != nil {
+			e.KeyID, e.KeyName = p.KeyID, p.Name
+		}
+		a.record(e)
+	})
+}
+
+func (a *Auditor) record(e AuditEntry) {
+	a.logger.Info("admin action",
+		"method", e.Method, "path", e.Path, "query", e.Query, "status", e.Status,
+		"key_id", e.KeyID, "remote_ip", e.RemoteIP, "request_id", e.RequestID)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, e)
+	if over := len(a.entries) - auditEntries; over > 0 {
+		a.entries = slices.Delete(a.entries, 0, over)
+	}
+	if err := a.store.Save(auditDoc, a.entries); err != nil {
+		a.logger.Error("persisting audit log failed", "error", err)
+	}
+}
+
+// Entries returns up to limit entries, newest first.
+func (a *Auditor) Entries(limit int) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := min(limit, len(a.entries))
+	out := make([]AuditEntry, 0, n)
+	for i := len(a.entries) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, a.entries[i])
+	}
+	return out
+}
+
+func scrubQuery(q url.Values) string {
+	q.Del("api_key")
+	return q.Encode()
+}
+
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"roadmapapi/internal/store"
+)
+
+func TestAuditEveryRequest(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuditor(st, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	requests := []struct{ method, url string }{
+		{http.MethodGet, "/admin/keys?api_key=secret&limit=5"},
+		{http.MethodHead, "/admin/config"},
+		{http.MethodDelete, "/admin/keys/key_1"},
+	}
+	for _, r := range requests {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.url, nil))
+	}
+
+	a, err = NewAuditor(st, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := a.Entries(10)
+	if len(got) != len(requests) {
+		t.Fatalf("audited %d requests, want %d", len(got), len(requests))
+	}
+	want := []AuditEntry{
+		{Method: http.MethodDelete, Path: "/admin/keys/key_1", Status: http.StatusNoContent},
+		{Method: http.MethodHead, Path: "/admin/config", Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/admin/keys", Query: "limit=5", Status: http.StatusOK},
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Method != w.Method || g.Path != w.Path || g.Query != w.Query || g.Status != w.Status {
+			t.Errorf("entry %d = %s %s?%s %d, want %s %s?%s %d", i, g.Method, g.Path, g.Query, g.Status, w.Method, w.Path, w.Query, w.Status)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"roadmapapi/internal/config"
+	"roadmapapi/internal/poller"
+	"roadmapapi/internal/problem"
+)
+
+// Source is the set of operations the admin API can run against one
+// upstream source.
+type Source struct {
+	Name          string
+	PurgeCache    func(url string) int
+	CachedURLs    func() map[string]time.Time
+	ExportTracker func() any
+	ResetTracker  func()
+	// Refresh re-fetches every column. It is used when the source has no
+	// poller to trigger.
+	Refresh func(ctx context.Context) error
+	Poller  *poller.Poller
+	// ReloadSession re-reads the source's upstream credentials and returns
+	// their status. It is nil for sources without credentials.
+	ReloadSession func() (any, error)
+}
+
+type Handlers struct {
+	cfg     *config.Config
+	level   *slog.LevelVar
+	audit   *Auditor
+	sources map[string]Source
+	names   []string
+	started time.Time
+}
+
+func NewHandlers(cfg *config.Config, level *slog.LevelVar, audit *Auditor, sources ...Source) *Handlers {
+	h := &Handlers{
+		cfg:     cfg,
+		level:   level,
+		audit:   audit,
+		sources: make(map[string]Source, len(sources)),
+		started: time.Now(),
+	}
+	for _, s := range sources {
+		h.sources[s.Name] = s
+		h.names = append(h.names, s.Name)
+	}
+	sort.Strings(h.names)
+	return h
+}
+
+// Routes mounts the admin endpoints. Callers are expected to guard them with
+// the admin scope and Auditor.Middleware.
+func (h *Handlers) Routes(r chi.Router) {
+	r.Get("/config", h.runtimeConfig)
+	r.Get("/audit", h.auditLog)
+
+	r.Get("/cache", h.listCache)
+	r.Delete("/cache", h.purgeCache)
+	r.Delete("/cache/{source}", h.purgeCache)
+
+	r.Post("/sources/{source}/refresh", h.refresh)
+	r.Post("/sources/{source}/session/reload", h.reloadSession)
+
+	r.Get("/tracker/{source}", h.exportTracker)
+	r.Delete("/tracker/{source}", h.resetTracker)
+
+	r.Get("/webhooks", h.webhooks)
+}
+
+// selected resolves the {source} URL parameter; an empty parameter selects
+// every source.
+func (h *Handlers) selected(w http.ResponseWriter, r *http.Request) ([]Source, bool) {
+	name := chi.URLParam(r, "source")
+	if name == "" || name == "all" {
+		out := make([]Source, 0, len(h.names))
+		for _, n := range h.names {
+			out = append(out, h.sources[n])
+		}
+		return out, true
+	}
+	s, ok := h.sources[name]
+	if !ok {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, fmt.Errorf("unknown or disabled source %q", name))
+		return nil, false
+	}
+	return []Source{s}, true
+}
+
+func (h *Handlers) runtimeConfig(w http.ResponseWriter, _ *http.Request) {
+	pollers := make(map[string]poller.Status)
+	for _, n := range h.names {
+		if p := h.sources[n].Poller; p != nil {
+			pollers[n] = p.Status()
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"config": h.cfg.Redacted(),
+		"runtime": map[string]any{
+			"logLevel":      h.level.Level().String(),
+			"startedAt":     h.started.UTC().Format(time.RFC3339),
+			"uptimeSeconds": int64(time.Since(h.started).Seconds()),
+			"sources":       h.names,
+			"pollers":       pollers,
+		},
+	})
+}
+
+func (h *Handlers) auditLog(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = min(v, auditEntries)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": h.audit.Entries(limit)})
+}
+
+func (h *Handlers) listCache(w http.ResponseWriter, _ *http.Request) {
+	type entry struct {
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expiresAt"`
+		Expired   bool      `json:"expired"`
+	}
+	now := time.Now()
+	out := make(map[string][]entry, len(h.names))
+	for _, n := range h.names {
+		urls := h.sources[n].CachedURLs()
+		list := make([]entry, 0, len(urls))
+		for u, exp := range urls {
+			list = append(list, entry{URL: u, ExpiresAt: exp, Expired: now.After(exp)})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+		out[n] = list
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"cache": out})
+}
+
+// purgeCache drops cached upstream responses for all sources or one, limited
+// to a single upstream URL when ?url= is given.
+func (h *Handlers) purgeCache(w http.ResponseWriter, r *http.Request) {
+	sources, ok := h.selected(w, r)
+	if !ok {
+		return
+	}
+	u := r.URL.Query().Get("url")
+	purged := make(map[string]int, len(sources))
+	for _, s := range sources {
+		purged[s.Name] = s.PurgeCache(u)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"purged": purged})
+}
+
+// refresh purges a source's cache and re-fetches it: through its poller when
+// polling is enabled, otherwise synchronously within the request.
+func (h *Handlers) refresh(w http.ResponseWriter, r *http.Request) {
+	sources, ok := h.selected(w, r)
+	if !ok {
+		return
+	}
+	type result struct {
+		Status     string `json:"status"`
+		DurationMs int64  `json:"durationMs,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}
+	code := http.StatusOK
+	out := make(map[string]result, len(sources))
+	for _, s := range sources {
+		s.PurgeCache("")
+		if s.Poller != nil {
+			s.Poller.Trigger()
+			out[s.Name] = result{Status: "triggered"}
+			code = http.StatusAccepted
+			continue
+		}
+		start := time.Now()
+		res := result{Status: "refreshed"}
+		if err := s.Refresh(r.Context()); err != nil {
+			res = result{Status: "failed", Error: err.Error()}
+			code = http.StatusBadGateway
+		}
+		res.DurationMs = time.Since(start).Milliseconds()
+		out[s.Name] = res
+	}
+	writeJSON(w, code, map[string]any{"sources": out})
+}
+
+func (h *Handlers) reloadSession(w http.ResponseWriter, r *http.Request) {
+	sources, ok := h.selected(w, r)
+	if !ok {
+		return
+	}
+	type result struct {
+		Status  string `json:"status"`
+		Session any    `json:"session,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+	code := http.StatusOK
+	out := make(map[string]result, len(sources))
+	for _, s := range sources {
+		if s.ReloadSession == nil {
+			if len(sources) == 1 {
+				problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, fmt.Errorf("source %q has no session", s.Name))
+				return
+			}
+			continue
+		}
+		st, err := s.ReloadSession()
+		res := result{Status: "reloaded", Session: st}
+		if err != nil {
+			res.Status, res.Error = "failed", err.Error()
+			code = http.StatusInternalServerError
+		}
+		out[s.Name] = res
+	}
+	writeJSON(w, code, map[string]any{"sources": out})
+}
+
+func (h *Handlers) exportTracker(w http.ResponseWriter, r *http.Request) {
+	sources, ok := h.selected(w, r)
+	if !ok {
+		return
+	}
+	out := make(map[string]any, len(sources))
+	for _, s := range sources {
+		out[s.Name] = s.ExportTracker()
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="tracker.json"`)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"exportedAt": time.Now().UTC().Format(time.RFC3339),
+		"trackers":   out,
+	})
+}
+
+func (h *Handlers) resetTracker(w http.ResponseWriter, r *http.Request) {
+	sources, ok := h.selected(w, r)
+	if !ok {
+		return
+	}
+	reset := make([]string, 0, len(sources))
+	for _, s := range sources {
+		s.ResetTracker()
+		reset = append(reset, s.Name)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reset": reset})
+}
+
+// webhooks answers 501: the service does not deliver webhooks yet, so there
+// are no subscriptions to list.
+func (h *Handlers) webhooks(w http.ResponseWriter, r *http.Request) {
+	problem.Error(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, errors.New("webhook subscriptions are not supported by this server"))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
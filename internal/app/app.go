@@ -0,0 +1,438 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/admin"
+	"roadmapapi/internal/archive"
+	"roadmapapi/internal/auth"
+	"roadmapapi/internal/config"
+	"roadmapapi/internal/cors"
+	"roadmapapi/internal/cubecraft"
+	"roadmapapi/internal/health"
+	"roadmapapi/internal/history"
+	"roadmapapi/internal/hive"
+	"roadmapapi/internal/poller"
+	"roadmapapi/internal/ratelimit"
+	"roadmapapi/internal/roadmap"
+	"roadmapapi/internal/store"
+)
+
+// App owns the long-lived components shared by the router and the server
+// lifecycle: upstream clients, services, background pollers and persistent
+// state.
+type App struct {
+	Config   *config.Config
+	Logger   *slog.Logger
+	LogLevel *slog.LevelVar
+
+	HiveClient  *hive.Client
+	HiveService hive.Service
+	// NotionBoards holds CubeCraft and any configured notionBoards.
+	NotionBoards []NotionBoard
+
+	Pollers map[string]*poller.Poller
+	Health  *health.Checker
+
+	Store *store.Store
+	// Auth is nil when auth.enabled is false.
+	Auth *auth.Manager
+	// RateLimit is nil when rateLimit.enabled is false.
+	RateLimit *ratelimit.IPLimiter
+	// CORS is nil when cors.enabled is false.
+	CORS *cors.CORS
+
+	Audit *admin.Auditor
+	Admin *admin.Handlers
+	// History is nil when history.enabled is false.
+	History *history.Recorder
+	// Archive is nil when archive.enabled is false.
+	Archive *archive.Archive
+
+	stop context.CancelFunc
+}
+
+// NotionBoard is a Notion-backed source, served under /<Name>.
+type NotionBoard struct {
+	Name    string
+	Client  *cubecraft.Client
+	Service cubecraft.Service
+	// CookieReloadInterval is how often Start checks the cookie file.
+	CookieReloadInterval time.Duration
+}
+
+func New(cfg *config.Config, logger *slog.Logger, level *slog.LevelVar) (*App, error) {
+	a := &App{
+		Config:   cfg,
+		Logger:   logger,
+		LogLevel: level,
+		Pollers:  make(map[string]*poller.Poller),
+	}
+
+	st, err := store.Open(cfg.Store.Dir)
+	if err != nil {
+		return nil, err
+	}
+	a.Store = st
+	if cfg.Auth.Enabled {
+		a.Auth, err = auth.NewManager(st, auth.Options{
+			AdminKey:      cfg.Auth.AdminKey.Value(),
+			AnonymousRead: cfg.Auth.AnonymousRead,
+			Defaults: auth.Limits{
+				RatePerMinute: cfg.Auth.RatePerMinute,
+				Burst:         cfg.Auth.Burst,
+				DailyQuota:    cfg.Auth.DailyQuota,
+			},
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	} else {
+		logger.Warn("auth is disabled, so the /admin endpoints are not served")
+	}
+	var sources []health.Source
+	var adminSources []admin.Source
+
+	if rl := cfg.RateLimit; rl.Enabled {
+		allow, err := ratelimit.ParseAllowList(rl.AllowList)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit allow list: %w", err)
+		}
+		a.RateLimit = ratelimit.NewIPLimiter(ratelimit.Config{
+			AllowList: allow,
+			Default:   ratelimit.PerMinute(rl.Default.RatePerMinute, rl.Default.Burst),
+			Crawl:     ratelimit.PerMinute(rl.Crawl.RatePerMinute, rl.Crawl.Burst),
+			Bypass:    ratelimit.PerMinute(rl.Bypass.RatePerMinute, rl.Bypass.Burst),
+		})
+	}
+
+	if cfg.CORS.Enabled {
+		if a.CORS, err = newCORS(cfg.CORS, cfg.Server.TrustProxy); err != nil {
+			return nil, fmt.Errorf("cors: %w", err)
+		}
+	}
+
+	boards := make(map[string]config.CubeCraftConfig)
+	var names, boardNames []string
+	if cfg.Hive.Enabled {
+		names = append(names, "hive")
+	}
+	if cfg.CubeCraft.Enabled {
+		boards["cubecraft"] = cfg.CubeCraft
+		boardNames = append(boardNames, "cubecraft")
+	}
+	for _, n := range cfg.NotionBoards {
+		if n.Enabled {
+			boards[n.Name] = n.CubeCraftConfig
+			boardNames = append(boardNames, n.Name)
+		}
+	}
+	names = append(names, boardNames...)
+	if cfg.History.Enabled {
+		if a.History, err = history.NewRecorder(st, cfg.History.Retention.Duration, names...); err != nil {
+			return nil, fmt.Errorf("history: %w", err)
+		}
+	}
+	if ac := cfg.Archive; ac.Enabled {
+		opts := archive.Options{Retention: ac.Retention.Duration, MaxSnapshots: ac.MaxSnapshots}
+		if a.Archive, err = archive.New(st, filepath.Join(st.Dir(), "snapshots"), opts, names...); err != nil {
+			return nil, fmt.Errorf("archive: %w", err)
+		}
+	}
+
+	if cfg.Hive.Enabled {
+		a.HiveClient = hive.NewClient(
+			cfg.Hive.BaseURL,
+			&http.Client{Timeout: cfg.Hive.Timeout.Duration},
+			hive.WithCacheTTL(cfg.Hive.CacheTTL.Duration),
+			hive.WithMaxConcurrency(cfg.Hive.MaxConcurrency),
+			hive.WithLogger(logger),
+		)
+		a.HiveService = hive.NewService(a.HiveClient)
+		src := health.Source{Name: "hive", Probe: a.HiveClient.Probe}
+		if cfg.Hive.PollInterval.Duration > 0 {
+			src.Poller = a.addPoller("hive", cfg.Hive.PollInterval.Duration, a.pollHive)
+		}
+		sources = append(sources, src)
+		adminSources = append(adminSources, admin.Source{
+			Name:          "hive",
+			PurgeCache:    a.HiveClient.PurgeCache,
+			CachedURLs:    a.HiveClient.CachedURLs,
+			ExportTracker: func() any { return a.HiveService.ExportTracker() },
+			ResetTracker:  a.HiveService.ResetTracker,
+			Refresh:       a.pollHive,
+			Poller:        src.Poller,
+		})
+	}
+
+	for _, name := range boardNames {
+		src, adm, err := a.addNotionBoard(name, boards[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sources = append(sources, src)
+		adminSources = append(adminSources, adm)
+	}
+
+	a.Health = health.NewChecker(health.Options{
+		ReadyMaxAge:   cfg.Health.ReadyMaxAge.Duration,
+		ProbeTimeout:  cfg.Health.ProbeTimeout.Duration,
+		ProbeCacheTTL: cfg.Health.ProbeCacheTTL.Duration,
+	}, sources...)
+
+	if a.Audit, err = admin.NewAuditor(st, logger); err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+	a.Admin = admin.NewHandlers(cfg, level, a.Audit, adminSources...)
+	return a, nil
+}
+
+// addNotionBoard sets up the client, service and poller of one Notion board.
+func (a *App) addNotionBoard(name string, cfg config.CubeCraftConfig) (health.Source, admin.Source, error) {
+	session, err := cubecraft.NewSession(cubecraft.SessionOptions{
+		Cookie:        cfg.Cookie.Value(),
+		CookieFile:    cfg.CookieFile,
+		ClientVersion: cfg.ClientVersion,
+		Logger:        a.Logger.With("source", name),
+	})
+	if err != nil {
+		return health.Source{}, admin.Source{}, err
+	}
+	client := cubecraft.NewClient(
+		cubecraft.WithSourceName(name),
+		cubecraft.WithBoard(notionBoard(cfg.Board)),
+		cubecraft.WithHTTPClient(&http.Client{Timeout: cfg.Timeout.Duration}),
+		cubecraft.WithAPIURL(cfg.APIURL),
+		cubecraft.WithSiteURL(cfg.SiteURL),
+		cubecraft.WithSession(session),
+		cubecraft.WithCacheTTL(cfg.CacheTTL.Duration),
+		cubecraft.WithPageContent(cfg.ContentConcurrency),
+		cubecraft.WithLogger(a.Logger),
+	)
+	nb := NotionBoard{Name: name, Client: client, Service: cubecraft.NewService(client), CookieReloadInterval: cfg.CookieReloadInterval.Duration}
+	a.NotionBoards = append(a.NotionBoards, nb)
+	poll := func(ctx context.Context) error { return a.pollNotion(ctx, nb) }
+	auth := func() health.AuthStatus {
+		st := session.Status()
+		return health.AuthStatus{OK: st.Authorized, Error: st.Error, Since: st.FailedAt}
+	}
+	src := health.Source{Name: name, Probe: client.Probe, Warnings: client.SchemaProblems, Auth: auth}
+	if cfg.PollInterval.Duration > 0 {
+		src.Poller = a.addPoller(name, cfg.PollInterval.Duration, poll)
+	}
+	return src, admin.Source{
+		Name:          name,
+		PurgeCache:    client.PurgeCache,
+		CachedURLs:    client.CachedURLs,
+		ExportTracker: func() any { return nb.Service.ExportTracker() },
+		ResetTracker:  nb.Service.ResetTracker,
+		Refresh:       poll,
+		Poller:        src.Poller,
+		ReloadSession: func() (any, error) {
+			err := session.Reload()
+			return session.Status(), err
+		},
+	}, nil
+}
+
+// notionBoard converts a board mapping from config; nil is the built-in
+// CubeCraft board.
+func notionBoard(c *config.NotionBoardConfig) cubecraft.Board {
+	if c == nil {
+		return cubecraft.DefaultBoard()
+	}
+	prop := func(p config.NotionPropertyConfig) cubecraft.Property {
+		return cubecraft.Property{Key: p.Key, Name: p.Name, Type: p.Type}
+	}
+	b := cubecraft.Board{
+		SpaceID:      c.SpaceID,
+		CollectionID: c.CollectionID,
+		ViewID:       c.ViewID,
+		GroupBy:      prop(c.GroupBy),
+		Columns:      c.Columns,
+		Labels:       c.Labels,
+		Events:       c.Events,
+		Properties:   make(map[string]cubecraft.Property, len(c.Properties)),
+		SortBy:       c.SortBy,
+		TimeZone:     c.TimeZone,
+	}
+	for f, p := range c.Properties {
+		b.Properties[f] = prop(p)
+	}
+	for _, g := range c.Groups {
+		b.Groups = append(b.Groups, cubecraft.BoardGroup{Value: g.Value, Hidden: g.Hidden})
+	}
+	return b
+}
+
+// newCORS builds the CORS middleware from config. /admin is always
+// same-origin only and cannot be overridden from config.
+func newCORS(cfg config.CORSConfig, trustProxy bool) (*cors.CORS, error) {
+	def := cors.Policy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge.Duration,
+	}
+	overrides := []cors.Override{{Path: "/admin", SameOrigin: true}}
+	for _, o := range cfg.Overrides {
+		p := def
+		if o.AllowedOrigins != nil {
+			p.AllowedOrigins = o.AllowedOrigins
+		}
+		if o.AllowedMethods != nil {
+			p.AllowedMethods = o.AllowedMethods
+		}
+		if o.AllowedHeaders != nil {
+			p.AllowedHeaders = o.AllowedHeaders
+		}
+		if o.AllowCredentials != nil {
+			p.AllowCredentials = *o.AllowCredentials
+		}
+		overrides = append(overrides, cors.Override{Path: o.Path, SameOrigin: o.SameOrigin, Policy: p})
+	}
+	return cors.New(def, trustProxy, overrides...)
+}
+
+func (a *App) addPoller(name string, interval time.Duration, fn poller.Func) *poller.Poller {
+	p := poller.New(name, interval, interval, fn, a.Logger)
+	a.Pollers[name] = p
+	return p
+}
+
+func (a *App) pollHive(ctx context.Context) error {
+	seen := make(map[string][]roadmap.Page)
+	for col := range a.HiveClient.Columns() {
+		q := hive.Query{
+			Column:        col,
+			SortBy:        "upvotes:desc",
+			IncludePinned: true,
+		}
+		pages, err := a.HiveService.GetAll(ctx, q)
+		if err != nil {
+			return fmt.Errorf("%s: %w", col, err)
+		}
+		seen[col] = pages
+	}
+	return a.record("hive", seen)
+}
+
+func (a *App) pollNotion(ctx context.Context, nb NotionBoard) error {
+	seen := make(map[string][]roadmap.Page)
+	for _, col := range nb.Service.Board().PollColumns() {
+		pages, err := nb.Service.All(ctx, col, 0, "")
+		if err != nil {
+			return fmt.Errorf("%s: %w", col, err)
+		}
+		// A partial board would show the missing cards as removed, and
+		// as added again once they load.
+		if t := nb.Service.Truncation(); t != nil {
+			return fmt.Errorf("%s: board incomplete, %d cards missing; poll not recorded", col, t.Missing)
+		}
+		for _, p := range pages {
+			seen[col] = append(seen[col], p.Roadmap())
+		}
+	}
+	return a.record(nb.Name, seen)
+}
+
+// record adds a complete poll to the history and the snapshot archive. Only
+// polls feed them, so they reflect a regular sampling rather than whatever
+// clients requested.
+func (a *App) record(source string, seen map[string][]roadmap.Page) error {
+	now := time.Now()
+	var errs []error
+	if a.History != nil {
+		obs := make(map[string][]history.Observation, len(seen))
+		for col, pages := range seen {
+			obs[col] = observations(pages)
+		}
+		if err := a.History.Observe(source, obs, now); err != nil {
+			errs = append(errs, fmt.Errorf("recording history: %w", err))
+		}
+	}
+	if a.Archive != nil {
+		items := make(map[string][]roadmap.Item, len(seen))
+		for col, pages := range seen {
+			for _, p := range pages {
+				items[col] = append(items[col], p.Items...)
+			}
+		}
+		if err := a.Archive.Record(source, items, now); err != nil {
+			errs = append(errs, fmt.Errorf("archiving snapshot: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func observations(pages []roadmap.Page) []history.Observation {
+	var out []history.Observation
+	for _, p := range pages {
+		for _, it := range p.Items {
+			out = append(out, history.Observation{
+				ID:       it.ID,
+				Title:    it.Title,
+				Status:   it.Status,
+				Category: it.Category,
+				Network:  it.Network,
+				Upvotes:  it.Upvotes,
+				ETA:      it.ETA,
+			})
+		}
+	}
+	return out
+}
+
+func (a *App) Start(ctx context.Context) {
+	ctx, a.stop = context.WithCancel(ctx)
+	for _, p := range a.Pollers {
+		p.Start(ctx)
+	}
+	for _, nb := range a.NotionBoards {
+		go nb.Client.Session().Watch(ctx, nb.CookieReloadInterval)
+	}
+	if a.Auth != nil {
+		a.Auth.Start(a.Config.Auth.FlushInterval.Duration)
+	}
+}
+
+// Shutdown stops the pollers together, letting in-flight runs finish within
+// ctx so that their change-tracking writes complete, stops the cookie
+// watchers and flushes API key usage.
+func (a *App) Shutdown(ctx context.Context) error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for name, p := range a.Pollers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Stop(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("poller %s: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if a.stop != nil {
+		a.stop()
+	}
+	if a.Auth != nil {
+		if err := a.Auth.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("auth: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
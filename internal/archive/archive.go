@@ -0,0 +1,301 @@
+package archive
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/roadmap"
+	"roadmapapi/internal/store"
+)
+
+var (
+	ErrNoSnapshot = errors.New("no snapshot")
+	validID       = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// Entry indexes one stored snapshot. Consecutive polls with identical
+// content share an entry: TakenAt is the first of them, LastSeen the latest.
+type Entry struct {
+	ID       string    `json:"id"`
+	TakenAt  time.Time `json:"takenAt"`
+	LastSeen time.Time `json:"lastSeen"`
+	Items    int       `json:"items"`
+	Size     int64     `json:"size"`
+}
+
+type Snapshot struct {
+	Entry
+	Source  string                    `json:"source"`
+	Columns map[string][]roadmap.Item `json:"columns"`
+}
+
+type Options struct {
+	// Retention drops snapshots that were superseded longer ago than this.
+	// Zero keeps them forever.
+	Retention time.Duration
+	// MaxSnapshots caps the snapshots kept per source. Zero means no cap.
+	MaxSnapshots int
+}
+
+// Archive stores gzip-compressed snapshots of every poll under dir, named by
+// the sha256 of their content, with a per-source index in the store.
+type Archive struct {
+	store *store.Store
+	dir   string
+	opts  Options
+
+	mu      sync.RWMutex
+	indexes map[string][]Entry
+}
+
+func New(st *store.Store, dir string, opts Options, sources ...string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	a := &Archive{
+		store:   st,
+		dir:     dir,
+		opts:    opts,
+		indexes: make(map[string][]Entry, len(sources)),
+	}
+	for _, s := range sources {
+		var idx []Entry
+		if _, err := st.Load(indexName(s), &idx); err != nil {
+			return nil, err
+		}
+		a.indexes[s] = idx
+	}
+	return a, nil
+}
+
+func indexName(source string) string { return "snapshots-" + source }
+
+func (a *Archive) path(id string) string {
+	return filepath.Join(a.dir, id[:2], id+".json.gz")
+}
+
+// Record archives one complete poll of a source. Items are normalized
+// before hashing so that identical boards produce identical snapshots.
+func (a *Archive) Record(source string, columns map[string][]roadmap.Item, at time.Time) error {
+	items := 0
+	normalized := make(map[string][]roadmap.Item, len(columns))
+	for col, list := range columns {
+		list = slices.Clone(list)
+		for i := range list {
+			list[i].Page = 0
+		}
+		slices.SortFunc(list, func(x, y roadmap.Item) int {
+			if c := cmp.Compare(y.Upvotes, x.Upvotes); c != 0 {
+				return c
+			}
+			return cmp.Compare(x.ID, y.ID)
+		})
+		normalized[col] = list
+		items += len(list)
+	}
+	body, err := json.Marshal(normalized)
+	if err != nil {
+		return fmt.Errorf("archive: encode: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	id := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	idx, ok := a.indexes[source]
+	if !ok {
+		return fmt.Errorf("archive: unknown source %q", source)
+	}
+	if n := len(idx); n > 0 && idx[n-1].ID == id {
+		idx[n-1].LastSeen = at
+		return a.store.Save(indexName(source), idx)
+	}
+	size, err := a.write(id, body)
+	if err != nil {
+		return err
+	}
+	idx = append(idx, Entry{ID: id, TakenAt: at, LastSeen: at, Items: items, Size: size})
+	idx = a.prune(idx, at)
+	a.indexes[source] = idx
+	if err := a.store.Save(indexName(source), idx); err != nil {
+		return err
+	}
+	return a.collect()
+}
+
+// write stores body under id unless an identical snapshot already exists.
+func (a *Archive) write(id string, body []byte) (int64, error) {
+	p := a.path(id)
+	if fi, err := os.Stat(p); err == nil {
+		return fi.Size(), nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return 0, fmt.Errorf("archive: compress: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("archive: compress: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), id+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+	return int64(buf.Len()), nil
+}
+
+// prune applies the retention rules. A snapshot is only dropped for age once
+// its successor is also past the cutoff, so the board as of the cutoff can
+// still be answered.
+func (a *Archive) prune(idx []Entry, now time.Time) []Entry {
+	if a.opts.Retention > 0 {
+		cutoff := now.Add(-a.opts.Retention)
+		drop := 0
+		for drop+1 < len(idx) && idx[drop+1].TakenAt.Before(cutoff) {
+			drop++
+		}
+		idx = idx[drop:]
+	}
+	if max := a.opts.MaxSnapshots; max > 0 && len(idx) > max {
+		idx = idx[len(idx)-max:]
+	}
+	return slices.Clip(idx)
+}
+
+// collect removes snapshot files no index refers to any more. The caller
+// holds the write lock.
+func (a *Archive) collect() error {
+	live := make(map[string]bool)
+	for _, idx := range a.indexes {
+		for _, e := range idx {
+			live[e.ID] = true
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(a.dir, "*", "*.json.gz"))
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	var errs []error
+	for _, f := range files {
+		id := filepath.Base(f)
+		id = id[:len(id)-len(".json.gz")]
+		if !live[id] {
+			if err := os.Remove(f); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Entries returns a source's snapshots, oldest first.
+func (a *Archive) Entries(source string) ([]Entry, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	idx, ok := a.indexes[source]
+	return slices.Clone(idx), ok
+}
+
+func (a *Archive) Sources() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]string, 0, len(a.indexes))
+	for s := range a.indexes {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// At returns the snapshot that was current at t: the newest one taken at or
+// before it.
+func (a *Archive) At(source string, t time.Time) (Snapshot, error) {
+	a.mu.RLock()
+	idx := a.indexes[source]
+	i := sort.Search(len(idx), func(i int) bool { return idx[i].TakenAt.After(t) })
+	if i == 0 {
+		a.mu.RUnlock()
+		return Snapshot{}, fmt.Errorf("%w of %s at or before %s", ErrNoSnapshot, source, t.Format(time.RFC3339))
+	}
+	e := idx[i-1]
+	a.mu.RUnlock()
+	return a.load(source, e)
+}
+
+// Get returns a snapshot by ID.
+func (a *Archive) Get(source, id string) (Snapshot, error) {
+	a.mu.RLock()
+	idx := a.indexes[source]
+	i := slices.IndexFunc(idx, func(e Entry) bool { return e.ID == id })
+	if i < 0 || !validID.MatchString(id) {
+		a.mu.RUnlock()
+		return Snapshot{}, fmt.Errorf("%w %q for %s", ErrNoSnapshot, id, source)
+	}
+	e := idx[i]
+	a.mu.RUnlock()
+	return a.load(source, e)
+}
+
+func (a *Archive) load(source string, e Entry) (Snapshot, error) {
+	f, err := os.Open(a.path(e.ID))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("archive: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("archive: %s: %w", e.ID, err)
+	}
+	s := Snapshot{Entry: e, Source: source}
+	if err := json.NewDecoder(zr).Decode(&s.Columns); err != nil {
+		return Snapshot{}, fmt.Errorf("archive: %s: %w", e.ID, err)
+	}
+	return s, nil
+}
+
+// Column implements roadmap.Archive.
+func (a *Archive) Column(source, column string, t time.Time) (roadmap.ArchivedColumn, error) {
+	s, err := a.At(source, t)
+	if errors.Is(err, ErrNoSnapshot) {
+		return roadmap.ArchivedColumn{}, fmt.Errorf("%w of %s at or before %s", roadmap.ErrNotArchived, source, t.Format(time.RFC3339))
+	}
+	if err != nil {
+		return roadmap.ArchivedColumn{}, err
+	}
+	return roadmap.ArchivedColumn{
+		SnapshotID: s.ID,
+		TakenAt:    s.TakenAt,
+		Items:      s.Columns[column],
+	}, nil
+}
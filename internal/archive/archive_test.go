@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := func(hours ...int) []Entry {
+		out := make([]Entry, len(hours))
+		for i, h := range hours {
+			at := t0.Add(time.Duration(h) * time.Hour)
+			out[i] = Entry{ID: at.Format("15h"), TakenAt: at, LastSeen: at}
+		}
+		return out
+	}
+	ids := func(idx []Entry) []string {
+		out := make([]string, len(idx))
+		for i, e := range idx {
+			out[i] = e.ID
+		}
+		return out
+	}
+	now := t0.Add(10 * time.Hour)
+	tests := []struct {
+		name string
+		opts Options
+		idx  []Entry
+		want []Entry
+	}{
+		{"no rules keeps everything", Options{}, entries(0, 2, 4, 6, 8, 10), entries(0, 2, 4, 6, 8, 10)},
+		{"retention keeps the snapshot current at the cutoff", Options{Retention: 5 * time.Hour}, entries(0, 2, 4, 6, 8, 10), entries(4, 6, 8, 10)},
+		{"successor taken exactly at the cutoff", Options{Retention: 4 * time.Hour}, entries(0, 2, 4, 6, 8, 10), entries(4, 6, 8, 10)},
+		{"short retention keeps the last superseded snapshot", Options{Retention: time.Minute}, entries(0, 2, 4, 6, 8, 10), entries(8, 10)},
+		{"retention longer than the history", Options{Retention: 48 * time.Hour}, entries(0, 2, 4, 6, 8, 10), entries(0, 2, 4, 6, 8, 10)},
+		{"single old snapshot is kept", Options{Retention: time.Hour}, entries(0), entries(0)},
+		{"max snapshots keeps the newest", Options{MaxSnapshots: 3}, entries(0, 2, 4, 6, 8, 10), entries(6, 8, 10)},
+		{"max snapshots above the count", Options{MaxSnapshots: 10}, entries(0, 2, 4), entries(0, 2, 4)},
+		{"retention then cap", Options{Retention: 5 * time.Hour, MaxSnapshots: 2}, entries(0, 2, 4, 6, 8, 10), entries(8, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Archive{opts: tt.opts}
+			got := a.prune(tt.idx, now)
+			if !reflect.DeepEqual(ids(got), ids(tt.want)) {
+				t.Errorf("prune() kept %v, want %v", ids(got), ids(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+
+	"roadmapapi/internal/roadmap"
+)
+
+type DiffItem struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Column string `json:"column"`
+	URL    string `json:"url,omitempty"`
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type Change struct {
+	DiffItem
+	Fields []FieldChange `json:"fields"`
+}
+
+type Diff struct {
+	Source  string     `json:"source"`
+	From    Entry      `json:"from"`
+	To      Entry      `json:"to"`
+	Added   []DiffItem `json:"added"`
+	Removed []DiffItem `json:"removed"`
+	Changed []Change   `json:"changed"`
+}
+
+// DiffFields are the item fields Compare looks at, in output order.
+// lastModified is left out: it changes with every edit and says nothing the
+// other fields don't.
+var DiffFields = []string{"column", "title", "status", "category", "network", "projectLead", "eta", "pinned", "upvotes", "contentText", "url"}
+
+type located struct {
+	column string
+	item   roadmap.Item
+}
+
+func index(s Snapshot) map[string]located {
+	out := make(map[string]located)
+	for col, items := range s.Columns {
+		for _, it := range items {
+			out[it.ID] = located{column: col, item: it}
+		}
+	}
+	return out
+}
+
+func (l located) field(name string) string {
+	it := l.item
+	switch name {
+	case "column":
+		return l.column
+	case "title":
+		return it.Title
+	case "status":
+		return it.Status
+	case "category":
+		return it.Category
+	case "network":
+		return it.Network
+	case "projectLead":
+		return it.ProjectLead
+	case "eta":
+		return it.ETA
+	case "pinned":
+		return strconv.FormatBool(it.Pinned)
+	case "upvotes":
+		return strconv.Itoa(it.Upvotes)
+	case "contentText":
+		return it.ContentText
+	case "url":
+		return it.URL
+	}
+	return ""
+}
+
+func (l located) diffItem() DiffItem {
+	return DiffItem{ID: l.item.ID, Title: l.item.Title, Column: l.column, URL: l.item.URL}
+}
+
+// Compare lists the items added and removed going from one snapshot to
+// another, and the field-level changes of items in both. Fields listed in
+// ignore are not compared.
+func Compare(from, to Snapshot, ignore ...string) Diff {
+	d := Diff{Source: to.Source, From: from.Entry, To: to.Entry, Added: []DiffItem{}, Removed: []DiffItem{}, Changed: []Change{}}
+	before, after := index(from), index(to)
+	for id, b := range before {
+		a, ok := after[id]
+		if !ok {
+			d.Removed = append(d.Removed, b.diffItem())
+			continue
+		}
+		var fields []FieldChange
+		for _, f := range DiffFields {
+			if slices.Contains(ignore, f) {
+				continue
+			}
+			if x, y := b.field(f), a.field(f); x != y {
+				fields = append(fields, FieldChange{Field: f, Before: x, After: y})
+			}
+		}
+		if fields != nil {
+			d.Changed = append(d.Changed, Change{DiffItem: a.diffItem(), Fields: fields})
+		}
+	}
+	for id, a := range after {
+		if _, ok := before[id]; !ok {
+			d.Added = append(d.Added, a.diffItem())
+		}
+	}
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].ID < d.Added[j].ID })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].ID < d.Removed[j].ID })
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].ID < d.Changed[j].ID })
+	return d
+}
@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"roadmapapi/internal/roadmap"
+)
+
+func TestCompare(t *testing.T) {
+	party := roadmap.Item{ID: "a", Title: "Party games", Status: "In Progress", Category: "Games", Upvotes: 5, URL: "https://example.com/a"}
+	lobby := roadmap.Item{ID: "b", Title: "Lobby rework", Status: "In Progress", Category: "Lobby"}
+	eggwars := roadmap.Item{ID: "c", Title: "EggWars maps", Status: "Coming Next", ETA: "2025-04-01T00:00:00Z"}
+	with := func(it roadmap.Item, f func(*roadmap.Item)) roadmap.Item {
+		f(&it)
+		return it
+	}
+	from := Snapshot{Source: "hive", Columns: map[string][]roadmap.Item{
+		"in-progress": {party, lobby},
+		"coming-next": {eggwars},
+	}}
+
+	tests := []struct {
+		name    string
+		to      map[string][]roadmap.Item
+		ignore  []string
+		added   []DiffItem
+		removed []DiffItem
+		changed []Change
+	}{
+		{
+			name: "unchanged",
+			to:   from.Columns,
+		},
+		{
+			name: "added and removed",
+			to: map[string][]roadmap.Item{
+				"in-progress": {party, {ID: "d", Title: "Bedrock parity"}},
+				"coming-next": {eggwars},
+			},
+			added:   []DiffItem{{ID: "d", Title: "Bedrock parity", Column: "in-progress"}},
+			removed: []DiffItem{{ID: "b", Title: "Lobby rework", Column: "in-progress"}},
+		},
+		{
+			name: "moved and edited",
+			to: map[string][]roadmap.Item{
+				"in-progress": {with(party, func(it *roadmap.Item) { it.Upvotes = 7 }), lobby},
+				"released":    {with(eggwars, func(it *roadmap.Item) { it.Status, it.ETA = "Released", "" })},
+			},
+			changed: []Change{
+				{
+					DiffItem: DiffItem{ID: "a", Title: "Party games", Column: "in-progress", URL: "https://example.com/a"},
+					Fields:   []FieldChange{{Field: "upvotes", Before: "5", After: "7"}},
+				},
+				{
+					DiffItem: DiffItem{ID: "c", Title: "EggWars maps", Column: "released"},
+					Fields: []FieldChange{
+						{Field: "column", Before: "coming-next", After: "released"},
+						{Field: "status", Before: "Coming Next", After: "Released"},
+						{Field: "eta", Before: "2025-04-01T00:00:00Z", After: ""},
+					},
+				},
+			},
+		},
+		{
+			name: "ignored fields",
+			to: map[string][]roadmap.Item{
+				"in-progress": {with(party, func(it *roadmap.Item) { it.Upvotes, it.LastModified = 9, "2025-03-02T00:00:00Z" }), lobby},
+				"released":    {with(eggwars, func(it *roadmap.Item) { it.Status = "Released" })},
+			},
+			ignore: []string{"upvotes", "status"},
+			changed: []Change{{
+				DiffItem: DiffItem{ID: "c", Title: "EggWars maps", Column: "released"},
+				Fields:   []FieldChange{{Field: "column", Before: "coming-next", After: "released"}},
+			}},
+		},
+		{
+			name: "title change reports the new title",
+			to: map[string][]roadmap.Item{
+				"in-progress": {party, with(lobby, func(it *roadmap.Item) { it.Title = "Lobby 2.0" })},
+				"coming-next": {eggwars},
+			},
+			changed: []Change{{
+				DiffItem: DiffItem{ID: "b", Title: "Lobby 2.0", Column: "in-progress"},
+				Fields:   []FieldChange{{Field: "title", Before: "Lobby rework", After: "Lobby 2.0"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := Snapshot{Source: "hive", Columns: tt.to}
+			d := Compare(from, to, tt.ignore...)
+			if tt.added == nil {
+				tt.added = []DiffItem{}
+			}
+			if tt.removed == nil {
+				tt.removed = []DiffItem{}
+			}
+			if tt.changed == nil {
+				tt.changed = []Change{}
+			}
+			if !reflect.DeepEqual(d.Added, tt.added) {
+				t.Errorf("added = %+v, want %+v", d.Added, tt.added)
+			}
+			if !reflect.DeepEqual(d.Removed, tt.removed) {
+				t.Errorf("removed = %+v, want %+v", d.Removed, tt.removed)
+			}
+			if !reflect.DeepEqual(d.Changed, tt.changed) {
+				t.Errorf("changed = %+v, want %+v", d.Changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := Diff{
+		Source:  "hive",
+		From:    Entry{TakenAt: at},
+		To:      Entry{TakenAt: at.Add(24 * time.Hour)},
+		Added:   []DiffItem{{ID: "d", Title: "Bedrock *parity*", Column: "in-progress", URL: "https://example.com/d"}},
+		Removed: []DiffItem{{ID: "b", Title: "Lobby_rework", Column: "in-progress"}},
+		Changed: []Change{{
+			DiffItem: DiffItem{ID: "c", Title: "EggWars maps", Column: "released"},
+			Fields: []FieldChange{
+				{Field: "eta", Before: "2025-04-01", After: ""},
+				{Field: "contentText", Before: "uses `code`\nand lines", After: strings.Repeat("x", maxMarkdownValue+5)},
+			},
+		}},
+	}
+	want := "# Hive roadmap changes\n\n" +
+		"_2025-03-01T12:00:00Z to 2025-03-02T12:00:00Z_\n" +
+		"\n## Added (1)\n\n" +
+		"- [**Bedrock \\*parity\\***](https://example.com/d) (in-progress)\n" +
+		"\n## Removed (1)\n\n" +
+		"- **Lobby\\_rework** (was in-progress)\n" +
+		"\n## Changed (1)\n\n" +
+		"- **EggWars maps**\n" +
+		"  - eta: `2025-04-01` → _none_\n" +
+		"  - contentText: `uses 'code' and lines` → `" + strings.Repeat("x", maxMarkdownValue) + "…`\n"
+	if got := d.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	empty := Diff{Source: "cubecraft", From: d.From, To: d.To}
+	if got := empty.Markdown(); !strings.HasPrefix(got, "# CubeCraft roadmap changes") || !strings.HasSuffix(got, "\nNo changes.\n") {
+		t.Errorf("Markdown() of an empty diff = %q", got)
+	}
+}
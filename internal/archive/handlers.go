@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"roadmapapi/internal/problem"
+)
+
+type Handlers struct {
+	archive *Archive
+}
+
+func NewHandlers(a *Archive) *Handlers {
+	return &Handlers{archive: a}
+}
+
+// Routes returns the snapshot endpoints of one source, typically mounted
+// under /<source>/snapshots.
+func (h *Handlers) Routes(source string) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) { h.list(w, r, source) })
+		r.Get("/diff", func(w http.ResponseWriter, r *http.Request) { h.diff(w, r, source) })
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) { h.get(w, r, source) })
+	}
+}
+
+// Diff serves GET /diff?source=...&from=...&to=..., the changelog between
+// two points in time.
+func (h *Handlers) Diff(w http.ResponseWriter, r *http.Request) {
+	source := strings.ToLower(r.URL.Query().Get("source"))
+	if _, ok := h.archive.Entries(source); !ok {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("source must be one of [%s], got %q", strings.Join(h.archive.Sources(), ", "), source))
+		return
+	}
+	h.diff(w, r, source)
+}
+
+func (h *Handlers) list(w http.ResponseWriter, _ *http.Request, source string) {
+	entries, _ := h.archive.Entries(source)
+	// Newest first, which is what callers browsing the archive want.
+	out := make([]Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		out = append(out, entries[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"snapshots": out})
+}
+
+func (h *Handlers) get(w http.ResponseWriter, r *http.Request, source string) {
+	s, err := h.archive.Get(source, chi.URLParam(r, "id"))
+	if err != nil {
+		snapshotError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// diff compares two snapshots. from and to each take a snapshot ID or an
+// RFC 3339 time, which selects the snapshot current at that time; to
+// defaults to the latest snapshot. ?ignore= skips fields, and
+// ?format=markdown or Accept: text/markdown renders release notes.
+func (h *Handlers) diff(w http.ResponseWriter, r *http.Request, source string) {
+	q := r.URL.Query()
+	if q.Get("from") == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, errors.New("from is required"))
+		return
+	}
+	var ignore []string
+	if raw := q.Get("ignore"); raw != "" {
+		for _, f := range strings.Split(raw, ",") {
+			f = strings.TrimSpace(f)
+			if !slices.Contains(DiffFields, f) {
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("ignore: unknown field %q (fields: %s)", f, strings.Join(DiffFields, ", ")))
+				return
+			}
+			ignore = append(ignore, f)
+		}
+	}
+	markdown := false
+	switch format := strings.ToLower(q.Get("format")); format {
+	case "":
+		markdown = strings.Contains(r.Header.Get("Accept"), "text/markdown")
+	case "markdown", "md":
+		markdown = true
+	case "json":
+	default:
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("format must be json or markdown, got %q", format))
+		return
+	}
+
+	from, err := h.resolve(source, q.Get("from"))
+	if err != nil {
+		snapshotError(w, r, err)
+		return
+	}
+	to, err := h.resolve(source, q.Get("to"))
+	if err != nil {
+		snapshotError(w, r, err)
+		return
+	}
+	d := Compare(from, to, ignore...)
+	if markdown {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, d.Markdown())
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (h *Handlers) resolve(source, ref string) (Snapshot, error) {
+	if ref == "" {
+		return h.archive.At(source, time.Now())
+	}
+	if validID.MatchString(ref) {
+		return h.archive.Get(source, ref)
+	}
+	t, err := time.Parse(time.RFC3339, ref)
+	if err != nil {
+		return Snapshot{}, badRef{fmt.Errorf("expected a snapshot ID or RFC 3339 time, got %q", ref)}
+	}
+	return h.archive.At(source, t)
+}
+
+type badRef struct{ error }
+
+func snapshotError(w http.ResponseWriter, r *http.Request, err error) {
+	var br badRef
+	switch {
+	case errors.As(err, &br):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err)
+	case errors.Is(err, ErrNoSnapshot):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, err)
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
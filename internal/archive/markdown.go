@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxMarkdownValue bounds field values in Markdown, where long content
+// would drown out the changelog.
+const maxMarkdownValue = 120
+
+// Markdown renders d as release-notes style Markdown.
+func (d Diff) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s roadmap changes\n\n", titleCase(d.Source))
+	fmt.Fprintf(&b, "_%s to %s_\n", d.From.TakenAt.UTC().Format(time.RFC3339), d.To.TakenAt.UTC().Format(time.RFC3339))
+	if len(d.Added)+len(d.Removed)+len(d.Changed) == 0 {
+		b.WriteString("\nNo changes.\n")
+		return b.String()
+	}
+	if len(d.Added) > 0 {
+		fmt.Fprintf(&b, "\n## Added (%d)\n\n", len(d.Added))
+		for _, it := range d.Added {
+			fmt.Fprintf(&b, "- %s (%s)\n", link(it), it.Column)
+		}
+	}
+	if len(d.Removed) > 0 {
+		fmt.Fprintf(&b, "\n## Removed (%d)\n\n", len(d.Removed))
+		for _, it := range d.Removed {
+			fmt.Fprintf(&b, "- %s (was %s)\n", link(it), it.Column)
+		}
+	}
+	if len(d.Changed) > 0 {
+		fmt.Fprintf(&b, "\n## Changed (%d)\n\n", len(d.Changed))
+		for _, c := range d.Changed {
+			fmt.Fprintf(&b, "- %s\n", link(c.DiffItem))
+			for _, f := range c.Fields {
+				fmt.Fprintf(&b, "  - %s: %s → %s\n", f.Field, mdValue(f.Before), mdValue(f.After))
+			}
+		}
+	}
+	return b.String()
+}
+
+func link(it DiffItem) string {
+	title := escape(it.Title)
+	if it.URL == "" {
+		return "**" + title + "**"
+	}
+	return "[**" + title + "**](" + it.URL + ")"
+}
+
+func mdValue(v string) string {
+	if v == "" {
+		return "_none_"
+	}
+	v = strings.Join(strings.Fields(v), " ")
+	if r := []rune(v); len(r) > maxMarkdownValue {
+		v = string(r[:maxMarkdownValue]) + "…"
+	}
+	return "`" + strings.ReplaceAll(v, "`", "'") + "`"
+}
+
+var mdEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`", "<", "&lt;")
+
+func escape(s string) string { return mdEscaper.Replace(s) }
+
+func titleCase(s string) string {
+	switch s {
+	case "hive":
+		return "Hive"
+	case "cubecraft":
+		return "CubeCraft"
+	}
+	return s
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"roadmapapi/internal/problem"
+)
+
+type keyOut struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Prefix        string    `json:"prefix"`
+	Scopes        []Scope   `json:"scopes"`
+	RatePerMinute float64   `json:"ratePerMinute"`
+	Burst         int       `json:"burst"`
+	DailyQuota    int       `json:"dailyQuota"`
+	Disabled      bool      `json:"disabled"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastUsedAt    time.Time `json:"lastUsedAt,omitzero"`
+	UsageToday    int       `json:"usageToday"`
+}
+
+func toKeyOut(k *Key, u Usage) keyOut {
+	return keyOut{
+		ID:            k.ID,
+		Name:          k.Name,
+		Prefix:        k.Prefix,
+		Scopes:        k.Scopes,
+		RatePerMinute: k.RatePerMinute,
+		Burst:         k.Burst,
+		DailyQuota:    k.DailyQuota,
+		Disabled:      k.Disabled,
+		CreatedAt:     k.CreatedAt,
+		LastUsedAt:    k.LastUsedAt,
+		UsageToday:    u.Count,
+	}
+}
+
+// Routes mounts the key management API. Callers are expected to guard it
+// with Require(ScopeAdmin).
+func (m *Manager) Routes(r chi.Router) {
+	r.Get("/", m.listKeys)
+	r.Post("/", m.createKey)
+	r.Get("/{id}", m.getKey)
+	r.Patch("/{id}", m.updateKey)
+	r.Delete("/{id}", m.deleteKey)
+}
+
+func (m *Manager) listKeys(w http.ResponseWriter, _ *http.Request) {
+	keys, usage := m.List()
+	out := make([]keyOut, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, toKeyOut(k, usage[k.ID]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": out})
+}
+
+func (m *Manager) createKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidBody, err)
+		return
+	}
+	k, tok, err := m.Create(req)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidBody, err)
+		return
+	}
+	m.logger.Info("api key created", "key_id", k.ID, "name", k.Name, "scopes", k.Scopes,
+		"by", FromContext(r.Context()).KeyID)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"key":   toKeyOut(k, Usage{}),
+		"token": tok,
+	})
+}
+
+func (m *Manager) getKey(w http.ResponseWriter, r *http.Request) {
+	k, u, err := m.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toKeyOut(k, u))
+}
+
+func (m *Manager) updateKey(w http.ResponseWriter, r *http.Request) {
+	var req UpdateRequest
+	if err := decodeBody(r, &req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidBody, err)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	k, err := m.Update(id, req)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, err)
+		return
+	case err != nil:
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidBody, err)
+		return
+	}
+	m.logger.Info("api key updated", "key_id", id, "by", FromContext(r.Context()).KeyID)
+	_, u, _ := m.Get(id)
+	writeJSON(w, http.StatusOK, toKeyOut(k, u))
+}
+
+func (m *Manager) deleteKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := m.Delete(id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, err)
+		} else {
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, err)
+		}
+		return
+	}
+	m.logger.Info("api key deleted", "key_id", id, "by", FromContext(r.Context()).KeyID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errors.New("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/ratelimit"
+	"roadmapapi/internal/store"
+)
+
+type Scope string
+
+const (
+	ScopeRead          Scope = "read"
+	ScopeBypassCache   Scope = "bypass-cache"
+	ScopeAdmin         Scope = "admin"
+	ScopeWebhookManage Scope = "webhook-manage"
+)
+
+var knownScopes = []Scope{ScopeRead, ScopeBypassCache, ScopeAdmin, ScopeWebhookManage}
+
+func ParseScopes(raw []string) ([]Scope, error) {
+	out := make([]Scope, 0, len(raw))
+	for _, r := range raw {
+		s := Scope(strings.ToLower(strings.TrimSpace(r)))
+		if !slices.Contains(knownScopes, s) {
+			return nil, fmt.Errorf("unknown scope %q (use %s)", r, joinScopes(knownScopes))
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func joinScopes(ss []Scope) string {
+	parts := make([]string, len(ss))
+	for i, s := range ss {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Key is a stored API key. Only the SHA-256 of the token is kept; the token
+// itself is shown once, when the key is created.
+//
+// RatePerMinute, Burst and DailyQuota fall back to the configured defaults
+// when zero; a negative value means unlimited.
+type Key struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Hash          string    `json:"hash"`
+	Prefix        string    `json:"prefix"`
+	Scopes        []Scope   `json:"scopes"`
+	RatePerMinute float64   `json:"ratePerMinute"`
+	Burst         int       `json:"burst"`
+	DailyQuota    int       `json:"dailyQuota"`
+	Disabled      bool      `json:"disabled"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastUsedAt    time.Time `json:"lastUsedAt,omitzero"`
+}
+
+type Usage struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+type Limits struct {
+	RatePerMinute float64
+	Burst         int
+	DailyQuota    int
+}
+
+type Options struct {
+	// AdminKey, when set, authenticates with every scope and no limits. It
+	// lives in configuration rather than the store so there is always a way
+	// to create the first stored key.
+	AdminKey      string
+	AnonymousRead bool
+	Defaults      Limits
+}
+
+const storeDoc = "api-keys"
+
+type document struct {
+	Keys  []*Key            `json:"keys"`
+	Usage map[string]*Usage `json:"usage"`
+}
+
+type Manager struct {
+	store    *store.Store
+	logger   *slog.Logger
+	opts     Options
+	adminSum [sha256.Size]byte
+	limiter  *ratelimit.Limiter
+
+	mu     sync.Mutex
+	keys   map[string]*Key
+	byHash map[string]*Key
+	usage  map[string]*Usage
+	dirty  bool
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+var ErrNotFound = errors.New("key not found")
+
+func NewManager(st *store.Store, opts Options, logger *slog.Logger) (*Manager, error) {
+	m := &Manager{
+		store:   st,
+		logger:  logger,
+		opts:    opts,
+		limiter: ratelimit.NewLimiter(),
+		keys:    make(map[string]*Key),
+		byHash:  make(map[string]*Key),
+		usage:   make(map[string]*Usage),
+	}
+	if opts.AdminKey != "" {
+		m.adminSum = sha256.Sum256([]byte(opts.AdminKey))
+	}
+	var doc document
+	if _, err := st.Load(storeDoc, &doc); err != nil {
+		return nil, err
+	}
+	for _, k := range doc.Keys {
+		m.keys[k.ID] = k
+		m.byHash[k.Hash] = k
+	}
+	for id, u := range doc.Usage {
+		if _, ok := m.keys[id]; ok {
+			m.usage[id] = u
+		}
+	}
+	if opts.AdminKey == "" && !m.hasAdminKey() {
+		logger.Warn("auth enabled without an admin key; admin endpoints are unreachable until auth.adminKey is set")
+	}
+	return m, nil
+}
+
+func (m *Manager) hasAdminKey() bool {
+	for _, k := range m.keys {
+		if !k.Disabled && slices.Contains(k.Scopes, ScopeAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, enc func([]byte) string) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return enc(b)
+}
+
+func (m *Manager) lookup(token string) (*Principal, bool) {
+	if m.opts.AdminKey != "" {
+		sum := sha256.Sum256([]byte(token))
+		if subtle.ConstantTimeCompare(sum[:], m.adminSum[:]) == 1 {
+			return &Principal{KeyID: "config", Name: "config admin key", Scopes: []Scope{ScopeAdmin}}, true
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.byHash[hashToken(token)]
+	if k == nil || k.Disabled {
+		return nil, false
+	}
+	return &Principal{KeyID: k.ID, Name: k.Name, Scopes: slices.Clone(k.Scopes), limits: m.limitsFor(k)}, true
+}
+
+func (m *Manager) limitsFor(k *Key) Limits {
+	l := Limits{RatePerMinute: k.RatePerMinute, Burst: k.Burst, DailyQuota: k.DailyQuota}
+	if l.RatePerMinute == 0 {
+		l.RatePerMinute = m.opts.Defaults.RatePerMinute
+	}
+	if l.Burst == 0 {
+		l.Burst = m.opts.Defaults.Burst
+	}
+	if l.DailyQuota == 0 {
+		l.DailyQuota = m.opts.Defaults.DailyQuota
+	}
+	return l
+}
+
+// consume charges one request against a key's daily quota. It returns the
+// quota and the requests left today; ok is false once the quota is spent.
+func (m *Manager) consume(p *Principal, now time.Time) (quota, remaining int, ok bool) {
+	quota = p.limits.DailyQuota
+	day := now.UTC().Format(time.DateOnly)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.usage[p.KeyID]
+	if u == nil || u.Day != day {
+		u = &Usage{Day: day}
+		m.usage[p.KeyID] = u
+	}
+	if k := m.keys[p.KeyID]; k != nil {
+		k.LastUsedAt = now
+	}
+	m.dirty = true
+	if quota > 0 && u.Count >= quota {
+		return quota, 0, false
+	}
+	u.Count++
+	return quota, max(quota-u.Count, 0), true
+}
+
+type CreateRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	RatePerMinute float64  `json:"ratePerMinute"`
+	Burst         int      `json:"burst"`
+	DailyQuota    int      `json:"dailyQuota"`
+}
+
+// Create stores a new key and returns it together with its token, which is
+// not recoverable afterwards.
+func (m *Manager) Create(req CreateRequest) (*Key, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", errors.New("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{string(ScopeRead)}
+	}
+	scopes, err := ParseScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	token := "rk_" + randomString(24, base64.RawURLEncoding.EncodeToString)
+	k := &Key{
+		ID:            "key_" + randomString(6, hex.EncodeToString),
+		Name:          name,
+		Hash:          hashToken(token),
+		Prefix:        token[:7],
+		Scopes:        scopes,
+		RatePerMinute: req.RatePerMinute,
+		Burst:         req.Burst,
+		DailyQuota:    req.DailyQuota,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[k.ID] = k
+	m.byHash[k.Hash] = k
+	if err := m.saveLocked(); err != nil {
+		delete(m.keys, k.ID)
+		delete(m.byHash, k.Hash)
+		return nil, "", err
+	}
+	cp := *k
+	return &cp, token, nil
+}
+
+type UpdateRequest struct {
+	Name          *string   `json:"name"`
+	Scopes        *[]string `json:"scopes"`
+	RatePerMinute *float64  `json:"ratePerMinute"`
+	Burst         *int      `json:"burst"`
+	DailyQuota    *int      `json:"dailyQuota"`
+	Disabled      *bool     `json:"disabled"`
+}
+
+func (m *Manager) Update(id string, req UpdateRequest) (*Key, error) {
+	var scopes []Scope
+	if req.Scopes != nil {
+		var err error
+		if scopes, err = ParseScopes(*req.Scopes); err != nil {
+			return nil, err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.keys[id]
+	if k == nil {
+		return nil, ErrNotFound
+	}
+	prev := *k
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return nil, errors.New("name must not be empty")
+		}
+		k.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Scopes != nil {
+		k.Scopes = scopes
+	}
+	if req.RatePerMinute != nil {
+		k.RatePerMinute = *req.RatePerMinute
+	}
+	if req.Burst != nil {
+		k.Burst = *req.Burst
+	}
+	if req.DailyQuota != nil {
+		k.DailyQuota = *req.DailyQuota
+	}
+	if req.Disabled != nil {
+		k.Disabled = *req.Disabled
+	}
+	if err := m.saveLocked(); err != nil {
+		*k = prev
+		return nil, err
+	}
+	cp := *k
+	return &cp, nil
+}
+
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.keys[id]
+	if k == nil {
+		return ErrNotFound
+	}
+	delete(m.keys, id)
+	delete(m.byHash, k.Hash)
+	u := m.usage[id]
+	delete(m.usage, id)
+	if err := m.saveLocked(); err != nil {
+		m.keys[id] = k
+		m.byHash[k.Hash] = k
+		if u != nil {
+			m.usage[id] = u
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) Get(id string) (*Key, Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := m.keys[id]
+	if k == nil {
+		return nil, Usage{}, ErrNotFound
+	}
+	cp := *k
+	return &cp, m.usageLocked(id), nil
+}
+
+func (m *Manager) List() ([]*Key, map[string]Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Key, 0, len(m.keys))
+	usage := make(map[string]Usage, len(m.keys))
+	for id, k := range m.keys {
+		cp := *k
+		out = append(out, &cp)
+		usage[id] = m.usageLocked(id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, usage
+}
+
+func (m *Manager) usageLocked(id string) Usage {
+	today := time.Now().UTC().Format(time.DateOnly)
+	if u := m.usage[id]; u != nil && u.Day == today {
+		return *u
+	}
+	return Usage{Day: today}
+}
+
+func (m *Manager) saveLocked() error {
+	doc := document{Keys: make([]*Key, 0, len(m.keys)), Usage: m.usage}
+	for _, k := range m.keys {
+		doc.Keys = append(doc.Keys, k)
+	}
+	sort.Slice(doc.Keys, func(i, j int) bool { return doc.Keys[i].ID < doc.Keys[j].ID })
+	if err := m.store.Save(storeDoc, doc); err != nil {
+		return err
+	}
+	m.dirty = false
+	return nil
+}
+
+// Flush persists usage counters and last-used times, which are only kept in
+// memory between flushes.
+func (m *Manager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.dirty {
+		return nil
+	}
+	return m.saveLocked()
+}
+
+// Start flushes usage every interval until Shutdown.
+func (m *Manager) Start(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel, m.done = cancel, make(chan struct{})
+	go func() {
+		defer close(m.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := m.Flush(); err != nil {
+					m.logger.Error("flushing api key usage failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if m.cancel != nil {
+		m.cancel()
+		select {
+		case <-m.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return m.Flush()
+}
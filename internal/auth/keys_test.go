@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"roadmapapi/internal/store"
+)
+
+func newManager(t *testing.T, dir string, opts Options) *Manager {
+	t.Helper()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(st, opts, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestKeysSurviveReload(t *testing.T) {
+	dir := t.TempDir()
+	m := newManager(t, dir, Options{})
+	k, tok, err := m.Create(CreateRequest{Name: " ci ", Scopes: []string{"read", "bypass-cache"}, DailyQuota: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := m.lookup(tok)
+	if !ok {
+		t.Fatal("new key not found")
+	}
+	if _, _, ok := m.consume(p, time.Now()); !ok {
+		t.Fatal("first request over quota")
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	m = newManager(t, dir, Options{})
+	got, usage, err := m.Get(k.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ci" || got.Hash != k.Hash || len(got.Scopes) != 2 || got.DailyQuota != 10 {
+		t.Errorf("reloaded key = %+v, want %+v", got, k)
+	}
+	if usage.Count != 1 {
+		t.Errorf("reloaded usage = %+v, want one request", usage)
+	}
+	if _, ok := m.lookup(tok); !ok {
+		t.Error("token no longer authenticates after reload")
+	}
+}
+
+func TestRevokedKeys(t *testing.T) {
+	dir := t.TempDir()
+	m := newManager(t, dir, Options{})
+	disabled, disabledTok, err := m.Create(CreateRequest{Name: "disabled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, deletedTok, err := m.Create(CreateRequest{Name: "deleted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := true
+	if _, err := m.Update(disabled.ID, UpdateRequest{Disabled: &off}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*Manager{m, newManager(t, dir, Options{})} {
+		for name, tok := range map[string]string{"disabled": disabledTok, "deleted": deletedTok} {
+			if _, ok := m.lookup(tok); ok {
+				t.Errorf("%s key still authenticates", name)
+			}
+		}
+	}
+	if err := m.Delete(deleted.ID); err != ErrNotFound {
+		t.Errorf("deleting twice = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	m := newManager(t, t.TempDir(), Options{Defaults: Limits{DailyQuota: 2}})
+	day := time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)
+	p := &Principal{KeyID: "key_1", limits: Limits{DailyQuota: 2}}
+	steps := []struct {
+		at        time.Time
+		remaining int
+		ok        bool
+	}{
+		{day, 1, true},
+		{day.Add(time.Minute), 0, true},
+		{day.Add(2 * time.Minute), 0, false},
+		{day.Add(59 * time.Minute), 0, false},
+		// The quota resets at midnight UTC.
+		{day.Add(time.Hour), 1, true},
+	}
+	for i, s := range steps {
+		quota, remaining, ok := m.consume(p, s.at)
+		if quota != 2 || remaining != s.remaining || ok != s.ok {
+			t.Errorf("request %d: consume() = %d, %d, %v, want 2, %d, %v", i+1, quota, remaining, ok, s.remaining, s.ok)
+		}
+	}
+
+	unlimited := &Principal{KeyID: "key_2", limits: Limits{DailyQuota: -1}}
+	for i := range 5 {
+		if _, _, ok := m.consume(unlimited, day); !ok {
+			t.Fatalf("unlimited key refused on request %d", i+1)
+		}
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	m := &Manager{opts: Options{Defaults: Limits{RatePerMinute: 60, Burst: 10, DailyQuota: 1000}}}
+	tests := []struct {
+		name string
+		key  Key
+		want Limits
+	}{
+		{"defaults", Key{}, Limits{60, 10, 1000}},
+		{"overrides", Key{RatePerMinute: 5, Burst: 1, DailyQuota: 50}, Limits{5, 1, 50}},
+		{"unlimited", Key{RatePerMinute: -1, DailyQuota: -1}, Limits{-1, 10, -1}},
+	}
+	for _, tt := range tests {
+		if got := m.limitsFor(&tt.key); got != tt.want {
+			t.Errorf("%s: limitsFor() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
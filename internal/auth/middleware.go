@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/problem"
+	"roadmapapi/internal/ratelimit"
+	"roadmapapi/internal/tracing"
+)
+
+var authRequests = metrics.Default.NewCounterVec("roadmap_auth_requests_total",
+	"Authentication outcomes: anonymous, key, invalid, forbidden, rate_limited or quota_exceeded.",
+	"result")
+
+// Principal is the caller a request was authenticated as. Anonymous callers
+// have an empty KeyID.
+type Principal struct {
+	KeyID  string
+	Name   string
+	Scopes []Scope
+	limits Limits
+}
+
+func (p *Principal) Anonymous() bool { return p == nil || p.KeyID == "" }
+
+// Has reports whether p holds scope s. The admin scope implies every other.
+func (p *Principal) Has(s Scope) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Scopes, s) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// token reads the API key from the Authorization bearer token, the X-API-Key
+// header or the api_key query parameter, in that order.
+func token(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, v, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(v)
+		}
+	}
+	if v := r.Header.Get("X-API-Key"); v != "" {
+		return strings.TrimSpace(v)
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// Authenticate resolves the caller and enforces per-key rate limits and daily
+// quotas. Requests without a key continue as anonymous; what they may do is
+// decided by Require and GuardCacheBypass. A nil Manager disables auth.
+func (m *Manager) Authenticate(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tok := token(r)
+		if tok == "" {
+			p := &Principal{}
+			if m.opts.AnonymousRead {
+				p.Scopes = []Scope{ScopeRead}
+			}
+			authRequests.With("anonymous").Inc()
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+			return
+		}
+
+		p, ok := m.lookup(tok)
+		if !ok {
+			authRequests.With("invalid").Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="roadmap-api"`)
+			problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "invalid or disabled API key"))
+			return
+		}
+		tracing.SpanFromContext(r.Context()).SetAttrs(tracing.String("auth.key_id", p.KeyID))
+
+		if p.KeyID != "config" {
+			l := ratelimit.PerMinute(p.limits.RatePerMinute, p.limits.Burst)
+			res := m.limiter.Allow(p.KeyID, l)
+			if !l.Unlimited() {
+				ratelimit.SetHeaders(w.Header(), res)
+			}
+			if !res.Allowed {
+				authRequests.With("rate_limited").Inc()
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "rate limit exceeded for this API key"))
+				return
+			}
+
+			now := time.Now()
+			quota, remaining, ok := m.consume(p, now)
+			if quota > 0 {
+				w.Header().Set("X-Quota-Limit", strconv.Itoa(quota))
+				w.Header().Set("X-Quota-Remaining", strconv.Itoa(remaining))
+			}
+			if !ok {
+				authRequests.With("quota_exceeded").Inc()
+				midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+				w.Header().Set("Retry-After", ceilSeconds(midnight.Sub(now)))
+				problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeQuotaExceeded, fmt.Sprintf("daily quota of %d requests exhausted; resets at %s", quota, midnight.Format(time.RFC3339))))
+				return
+			}
+		}
+
+		authRequests.With("key").Inc()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+}
+
+// Require rejects callers without scope s: 401 for anonymous callers, 403
+// for keys lacking the scope. With auth disabled every caller passes, except
+// for the admin scope, which then nobody holds.
+func (m *Manager) Require(s Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if m == nil && s == ScopeAdmin {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "admin endpoints require auth to be enabled"))
+			})
+		}
+		if m == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := FromContext(r.Context())
+			if !p.Has(s) {
+				deny(w, r, p, fmt.Sprintf("this endpoint requires the %q scope", s))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GuardCacheBypass only lets callers with the bypass-cache scope send
+// cache=false, so anonymous traffic is always served from cache.
+func (m *Manager) GuardCacheBypass(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("cache"))) {
+		case "0", "false", "no", "n", "off":
+			if p := FromContext(r.Context()); !p.Has(ScopeBypassCache) {
+				deny(w, r, p, fmt.Sprintf("cache=false requires an API key with the %q scope", ScopeBypassCache))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func deny(w http.ResponseWriter, r *http.Request, p *Principal, msg string) {
+	authRequests.With("forbidden").Inc()
+	if p.Anonymous() {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="roadmap-api"`)
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, msg))
+		return
+	}
+	problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, msg))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
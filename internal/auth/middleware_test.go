@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	const admin = "0123456789abcdef0123"
+	m := newManager(t, t.TempDir(), Options{AdminKey: admin})
+	_, reader, err := m.Create(CreateRequest{Name: "reader", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, bypass, err := m.Create(CreateRequest{Name: "bypass", Scopes: []string{"read", "bypass-cache"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonRead := newManager(t, t.TempDir(), Options{AnonymousRead: true})
+
+	tests := []struct {
+		name  string
+		m     *Manager
+		scope Scope
+		url   string
+		token string
+		want  int
+	}{
+		{"read key reads", m, ScopeRead, "/hive/released", reader, http.StatusOK},
+		{"read key on admin", m, ScopeAdmin, "/admin/keys", reader, http.StatusForbidden},
+		{"config admin key on admin", m, ScopeAdmin, "/admin/keys", admin, http.StatusOK},
+		{"admin implies read", m, ScopeRead, "/hive/released", admin, http.StatusOK},
+		{"unknown key", m, ScopeRead, "/hive/released", "rk_nope", http.StatusUnauthorized},
+		{"anonymous without anonymous read", m, ScopeRead, "/hive/released", "", http.StatusUnauthorized},
+		{"anonymous with anonymous read", anonRead, ScopeRead, "/hive/released", "", http.StatusOK},
+		{"anonymous read does not reach admin", anonRead, ScopeAdmin, "/admin/keys", "", http.StatusUnauthorized},
+		{"cache bypass without the scope", m, ScopeRead, "/hive/released?cache=false", reader, http.StatusForbidden},
+		{"cache bypass with the scope", m, ScopeRead, "/hive/released?cache=false", bypass, http.StatusOK},
+		{"anonymous cache bypass", anonRead, ScopeRead, "/hive/released?cache=off", "", http.StatusUnauthorized},
+		{"auth disabled reads", nil, ScopeRead, "/hive/released?cache=false", "", http.StatusOK},
+		{"auth disabled admin", nil, ScopeAdmin, "/admin/keys", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.m.Authenticate(tt.m.Require(tt.scope)(tt.m.GuardCacheBypass(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))))
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaExhausted(t *testing.T) {
+	m := newManager(t, t.TempDir(), Options{})
+	_, tok, err := m.Create(CreateRequest{Name: "small", DailyQuota: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for i, want := range []struct {
+		code      int
+		remaining string
+	}{{http.StatusOK, "1"}, {http.StatusOK, "0"}, {http.StatusTooManyRequests, "0"}} {
+		req := httptest.NewRequest(http.MethodGet, "/hive/released", nil)
+		req.Header.Set("X-API-Key", tok)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want.code || rec.Header().Get("X-Quota-Remaining") != want.remaining {
+			t.Errorf("request %d: status %d, remaining %q; want %d, %q", i+1, rec.Code, rec.Header().Get("X-Quota-Remaining"), want.code, want.remaining)
+		}
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: no Retry-After on an exhausted quota", i+1)
+		}
+	}
+}
@@ -0,0 +1,768 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Server    ServerConfig    `json:"server"`
+	Log       LogConfig       `json:"log"`
+	Metrics   MetricsConfig   `json:"metrics"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Health    HealthConfig    `json:"health"`
+	Store     StoreConfig     `json:"store"`
+	History   HistoryConfig   `json:"history"`
+	Archive   ArchiveConfig   `json:"archive"`
+	Auth      AuthConfig      `json:"auth"`
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	CORS      CORSConfig      `json:"cors"`
+	Hive      HiveConfig      `json:"hive"`
+	CubeCraft CubeCraftConfig `json:"cubecraft"`
+	// NotionBoards are further public Notion roadmaps, each served under
+	// /<name>. They can only be set in the config file.
+	NotionBoards []NotionSourceConfig `json:"notionBoards"`
+}
+
+type ServerConfig struct {
+	Addr              string   `json:"addr"`
+	RequestTimeout    Duration `json:"requestTimeout"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout"`
+	IdleTimeout       Duration `json:"idleTimeout"`
+	MaxHeaderBytes    int      `json:"maxHeaderBytes"`
+	ShutdownTimeout   Duration `json:"shutdownTimeout"`
+	// HooksTimeout is the part of ShutdownTimeout kept for the shutdown
+	// hooks; draining connections gets the rest.
+	HooksTimeout Duration  `json:"hooksTimeout"`
+	TLS          TLSConfig `json:"tls"`
+	// ExposeUpstreamErrors adds the failed upstream endpoint, status and
+	// error to problem responses. Meant for debugging only.
+	ExposeUpstreamErrors bool `json:"exposeUpstreamErrors"`
+	// TrustProxy takes the client IP from X-Forwarded-For/X-Real-IP and the
+	// scheme from X-Forwarded-Proto. Only enable it behind a proxy that
+	// sets them.
+	TrustProxy bool `json:"trustProxy"`
+}
+
+type TLSConfig struct {
+	CertFile       string   `json:"certFile"`
+	KeyFile        string   `json:"keyFile"`
+	ReloadInterval Duration `json:"reloadInterval"`
+}
+
+func (t TLSConfig) Enabled() bool { return t.CertFile != "" || t.KeyFile != "" }
+
+type LogConfig struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+}
+
+type TracingConfig struct {
+	Enabled     bool     `json:"enabled"`
+	Exporter    string   `json:"exporter"`
+	Endpoint    string   `json:"endpoint"`
+	Headers     []string `json:"headers"`
+	ServiceName string   `json:"serviceName"`
+	SampleRatio float64  `json:"sampleRatio"`
+}
+
+type HealthConfig struct {
+	ReadyMaxAge   Duration `json:"readyMaxAge"`
+	ProbeTimeout  Duration `json:"probeTimeout"`
+	ProbeCacheTTL Duration `json:"probeCacheTTL"`
+}
+
+type StoreConfig struct {
+	Dir string `json:"dir"`
+}
+
+type HistoryConfig struct {
+	Enabled bool `json:"enabled"`
+	// Retention drops items that have not been seen for this long.
+	Retention   Duration `json:"retention"`
+	StatsMaxAge Duration `json:"statsMaxAge"`
+	// TrendingWindow is the default span /hive/trending measures over.
+	TrendingWindow Duration `json:"trendingWindow"`
+}
+
+// ArchiveConfig controls the snapshot archive, stored under
+// <store.dir>/snapshots.
+type ArchiveConfig struct {
+	Enabled bool `json:"enabled"`
+	// Retention drops snapshots superseded longer ago than this; 0 keeps
+	// them forever.
+	Retention Duration `json:"retention"`
+	// MaxSnapshots caps the snapshots kept per source; 0 means no cap.
+	MaxSnapshots int `json:"maxSnapshots"`
+}
+
+type AuthConfig struct {
+	Enabled       bool     `json:"enabled"`
+	AdminKey      Secret   `json:"adminKey"`
+	AnonymousRead bool     `json:"anonymousRead"`
+	RatePerMinute float64  `json:"ratePerMinute"`
+	Burst         int      `json:"burst"`
+	DailyQuota    int      `json:"dailyQuota"`
+	FlushInterval Duration `json:"flushInterval"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `json:"enabled"`
+	// AllowList holds IPs or CIDR prefixes that are never limited.
+	AllowList []string       `json:"allowList"`
+	Default   RateLimitClass `json:"default"`
+	Crawl     RateLimitClass `json:"crawl"`
+	Bypass    RateLimitClass `json:"bypass"`
+}
+
+type RateLimitClass struct {
+	RatePerMinute float64 `json:"ratePerMinute"`
+	Burst         int     `json:"burst"`
+}
+
+type CORSConfig struct {
+	Enabled          bool     `json:"enabled"`
+	AllowedOrigins   []string `json:"allowedOrigins"`
+	AllowedMethods   []string `json:"allowedMethods"`
+	AllowedHeaders   []string `json:"allowedHeaders"`
+	ExposedHeaders   []string `json:"exposedHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	MaxAge           Duration `json:"maxAge"`
+	// Overrides apply to every path under Path. Unset fields inherit from
+	// the top-level settings. They can only be set in the config file.
+	Overrides []CORSOverride `json:"overrides"`
+}
+
+type CORSOverride struct {
+	Path             string   `json:"path"`
+	SameOrigin       bool     `json:"sameOrigin"`
+	AllowedOrigins   []string `json:"allowedOrigins"`
+	AllowedMethods   []string `json:"allowedMethods"`
+	AllowedHeaders   []string `json:"allowedHeaders"`
+	AllowCredentials *bool    `json:"allowCredentials"`
+}
+
+type HiveConfig struct {
+	Enabled        bool     `json:"enabled"`
+	BaseURL        string   `json:"baseURL"`
+	Timeout        Duration `json:"timeout"`
+	CacheTTL       Duration `json:"cacheTTL"`
+	MaxConcurrency int      `json:"maxConcurrency"`
+	PollInterval   Duration `json:"pollInterval"`
+}
+
+type CubeCraftConfig struct {
+	Enabled bool   `json:"enabled"`
+	APIURL  string `json:"apiURL"`
+	SiteURL string `json:"siteURL"`
+	Cookie  Secret `json:"cookie"`
+	// CookieFile is read for the cookie instead, such as a mounted secret.
+	// It is reloaded on SIGHUP and, every CookieReloadInterval, when it has
+	// changed.
+	CookieFile           string   `json:"cookieFile"`
+	CookieReloadInterval Duration `json:"cookieReloadInterval"`
+	// ClientVersion is sent as notion-client-version.
+	ClientVersion string   `json:"clientVersion"`
+	Timeout       Duration `json:"timeout"`
+	CacheTTL      Duration `json:"cacheTTL"`
+	PollInterval  Duration `json:"pollInterval"`
+	// ContentConcurrency is how many card pages are loaded at once; 0 skips
+	// page content.
+	ContentConcurrency int `json:"contentConcurrency"`
+	// Board maps the Notion board onto columns. Unset means the built-in
+	// CubeCraft mapping; it can only be set in the config file.
+	Board *NotionBoardConfig `json:"board,omitempty"`
+}
+
+// NotionSourceConfig is a Notion board served alongside CubeCraft. Fields
+// left out of the file take the cubecraft defaults, except the URLs and the
+// board, which are required.
+type NotionSourceConfig struct {
+	Name string `json:"name"`
+	CubeCraftConfig
+}
+
+func (n *NotionSourceConfig) UnmarshalJSON(b []byte) error {
+	type plain NotionSourceConfig
+	d := Default().CubeCraft
+	d.APIURL, d.SiteURL = "", ""
+	p := plain{CubeCraftConfig: d}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	*n = NotionSourceConfig(p)
+	return nil
+}
+
+// NotionBoardConfig describes a public Notion board.
+type NotionBoardConfig struct {
+	SpaceID      string `json:"spaceId"`
+	CollectionID string `json:"collectionId"`
+	ViewID       string `json:"viewId"`
+	// GroupBy is the select property the board is grouped by.
+	GroupBy NotionPropertyConfig `json:"groupBy"`
+	// Groups lists every value of GroupBy in board order; hidden groups
+	// are loaded but not shown on the board. Defaults to the mapped values.
+	Groups []NotionGroupConfig `json:"groups"`
+	// Columns maps each column to the GroupBy values it shows.
+	Columns map[string][]string `json:"columns"`
+	// Labels renames GroupBy values in responses.
+	Labels map[string]string `json:"labels"`
+	// Events names the change events for cards moving into the listed
+	// GroupBy values; other moves are reported as status_change.
+	Events map[string][]string `json:"events"`
+	// Properties maps category, network, projectLead, releasedAt and
+	// releasePost to board properties.
+	Properties map[string]NotionPropertyConfig `json:"properties"`
+	// SortBy is a field from Properties or a property key.
+	SortBy   string `json:"sortBy"`
+	TimeZone string `json:"timeZone"`
+}
+
+// NotionPropertyConfig identifies a board property by name, looked up in
+// the collection schema, with Key as the fallback. In the file it can also
+// be given as just the key. Type, if set, is checked against the schema.
+type NotionPropertyConfig struct {
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+func (p *NotionPropertyConfig) UnmarshalJSON(b []byte) error {
+	var key string
+	if err := json.Unmarshal(b, &key); err == nil {
+		*p = NotionPropertyConfig{Key: key}
+		return nil
+	}
+	type plain NotionPropertyConfig
+	var v plain
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*p = NotionPropertyConfig(v)
+	return nil
+}
+
+func (p NotionPropertyConfig) empty() bool {
+	return strings.TrimSpace(p.Key) == "" && strings.TrimSpace(p.Name) == ""
+}
+
+type NotionGroupConfig struct {
+	Value  string `json:"value"`
+	Hidden bool   `json:"hidden"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			RequestTimeout:    Duration{30 * time.Second},
+			ReadTimeout:       Duration{15 * time.Second},
+			ReadHeaderTimeout: Duration{5 * time.Second},
+			WriteTimeout:      Duration{45 * time.Second},
+			IdleTimeout:       Duration{2 * time.Minute},
+			MaxHeaderBytes:    64 << 10,
+			ShutdownTimeout:   Duration{20 * time.Second},
+			HooksTimeout:      Duration{5 * time.Second},
+			TLS: TLSConfig{
+				ReloadInterval: Duration{time.Minute},
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "auto",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Exporter:    "stdout",
+			Endpoint:    "http://localhost:4318/v1/traces",
+			ServiceName: "roadmap-api",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			ReadyMaxAge:   Duration{10 * time.Minute},
+			ProbeTimeout:  Duration{10 * time.Second},
+			ProbeCacheTTL: Duration{30 * time.Second},
+		},
+		Store: StoreConfig{
+			Dir: "data",
+		},
+		History: HistoryConfig{
+			Enabled:        true,
+			Retention:      Duration{2 * 365 * 24 * time.Hour},
+			StatsMaxAge:    Duration{5 * time.Minute},
+			TrendingWindow: Duration{7 * 24 * time.Hour},
+		},
+		Archive: ArchiveConfig{
+			Enabled:      true,
+			Retention:    Duration{365 * 24 * time.Hour},
+			MaxSnapshots: 5000,
+		},
+		Auth: AuthConfig{
+			Enabled:       false,
+			AnonymousRead: true,
+			RatePerMinute: 120,
+			Burst:         30,
+			DailyQuota:    0,
+			FlushInterval: Duration{30 * time.Second},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimitClass{RatePerMinute: 120, Burst: 40},
+			Crawl:   RateLimitClass{RatePerMinute: 20, Burst: 5},
+			Bypass:  RateLimitClass{RatePerMinute: 4, Burst: 2},
+		},
+		CORS: CORSConfig{
+			Enabled:        true,
+			AllowedMethods: []string{"GET", "HEAD"},
+			AllowedHeaders: []string{"Authorization", "X-API-Key", "Content-Type"},
+			ExposedHeaders: []string{
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+				"Retry-After", "X-Quota-Limit", "X-Quota-Remaining",
+			},
+			MaxAge: Duration{10 * time.Minute},
+		},
+		Hive: HiveConfig{
+			Enabled:        true,
+			BaseURL:        "https://updates.playhive.com/api/v1/submission",
+			Timeout:        Duration{12 * time.Second},
+			CacheTTL:       Duration{30 * time.Second},
+			MaxConcurrency: 4,
+			PollInterval:   Duration{time.Minute},
+		},
+		CubeCraft: CubeCraftConfig{
+			Enabled:              true,
+			APIURL:               "https://cubecraft.notion.site/api/v3",
+			SiteURL:              "https://cubecraft.notion.site/e86c96a3ee78465d8e5c24c22489c094",
+			CookieReloadInterval: Duration{time.Minute},
+			ClientVersion:        "23.13.0.5155",
+			Timeout:              Duration{30 * time.Second},
+			CacheTTL:             Duration{2 * time.Minute},
+			PollInterval:         Duration{2 * time.Minute},
+			ContentConcurrency:   4,
+		},
+	}
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if strings.TrimSpace(c.Server.Addr) == "" {
+		add("server.addr", "must not be empty")
+	}
+	if c.Server.RequestTimeout.Duration <= 0 {
+		add("server.requestTimeout", "must be positive")
+	}
+	for _, d := range []struct {
+		field string
+		value Duration
+	}{
+		{"server.readTimeout", c.Server.ReadTimeout},
+		{"server.readHeaderTimeout", c.Server.ReadHeaderTimeout},
+		{"server.writeTimeout", c.Server.WriteTimeout},
+		{"server.idleTimeout", c.Server.IdleTimeout},
+		{"server.shutdownTimeout", c.Server.ShutdownTimeout},
+		{"server.hooksTimeout", c.Server.HooksTimeout},
+	} {
+		if d.value.Duration <= 0 {
+			add(d.field, "must be positive")
+		}
+	}
+	if c.Server.HooksTimeout.Duration >= c.Server.ShutdownTimeout.Duration {
+		add("server.hooksTimeout", "must be shorter than server.shutdownTimeout (%s), which it is part of", c.Server.ShutdownTimeout)
+	}
+	if c.Server.WriteTimeout.Duration > 0 && c.Server.WriteTimeout.Duration <= c.Server.RequestTimeout.Duration {
+		add("server.writeTimeout", "must be longer than server.requestTimeout (%s) or slow responses are cut off", c.Server.RequestTimeout)
+	}
+	if c.Server.MaxHeaderBytes < 1<<10 {
+		add("server.maxHeaderBytes", "must be at least 1024")
+	}
+	if t := c.Server.TLS; t.Enabled() {
+		if t.CertFile == "" || t.KeyFile == "" {
+			add("server.tls", "certFile and keyFile must be set together")
+		}
+		if t.ReloadInterval.Duration < 0 {
+			add("server.tls.reloadInterval", "must not be negative")
+		}
+	}
+
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		add("log.level", "unknown level %q (use debug, info, warn or error)", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "auto", "json", "text", "color":
+	default:
+		add("log.format", "must be one of auto, json, text, color; got %q", c.Log.Format)
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		add("metrics.path", "must start with \"/\"")
+	}
+
+	if t := c.Tracing; t.Enabled {
+		switch t.Exporter {
+		case "stdout":
+		case "otlp":
+			if err := validateURL(t.Endpoint); err != nil {
+				add("tracing.endpoint", "%v", err)
+			}
+		default:
+			add("tracing.exporter", "must be stdout or otlp, got %q", t.Exporter)
+		}
+		if t.SampleRatio < 0 || t.SampleRatio > 1 {
+			add("tracing.sampleRatio", "must be between 0 and 1")
+		}
+		for _, h := range t.Headers {
+			if k, _, ok := strings.Cut(h, "="); !ok || strings.TrimSpace(k) == "" {
+				add("tracing.headers", "expected key=value, got %q", h)
+			}
+		}
+	}
+
+	if c.Health.ReadyMaxAge.Duration <= 0 {
+		add("health.readyMaxAge", "must be positive")
+	}
+	if c.Health.ProbeTimeout.Duration <= 0 {
+		add("health.probeTimeout", "must be positive")
+	}
+	if c.Health.ProbeCacheTTL.Duration < 0 {
+		add("health.probeCacheTTL", "must not be negative")
+	}
+
+	if strings.TrimSpace(c.Store.Dir) == "" {
+		add("store.dir", "must not be empty")
+	}
+
+	if h := c.History; h.Enabled {
+		if h.Retention.Duration < 0 {
+			add("history.retention", "must not be negative (0 keeps items forever)")
+		}
+		if h.StatsMaxAge.Duration <= 0 {
+			add("history.statsMaxAge", "must be positive")
+		}
+		if w := h.TrendingWindow.Duration; w <= 0 || w > 90*24*time.Hour {
+			add("history.trendingWindow", "must be positive and at most 2160h")
+		}
+	}
+
+	if a := c.Archive; a.Enabled {
+		if a.Retention.Duration < 0 {
+			add("archive.retention", "must not be negative (0 keeps snapshots forever)")
+		}
+		if a.MaxSnapshots < 0 {
+			add("archive.maxSnapshots", "must not be negative (0 means no cap)")
+		}
+	}
+
+	if a := c.Auth; a.Enabled {
+		if a.AdminKey != "" && len(a.AdminKey) < 16 {
+			add("auth.adminKey", "must be at least 16 characters")
+		}
+		if a.RatePerMinute < 0 {
+			add("auth.ratePerMinute", "must not be negative (0 disables the default limit)")
+		}
+		if a.Burst < 0 {
+			add("auth.burst", "must not be negative")
+		}
+		if a.DailyQuota < 0 {
+			add("auth.dailyQuota", "must not be negative (0 means unlimited)")
+		}
+		if a.FlushInterval.Duration <= 0 {
+			add("auth.flushInterval", "must be positive")
+		}
+	}
+
+	if rl := c.RateLimit; rl.Enabled {
+		for _, e := range rl.AllowList {
+			if err := validateIPOrPrefix(e); err != nil {
+				add("rateLimit.allowList", "%v", err)
+			}
+		}
+		for _, cl := range []struct {
+			name string
+			c    RateLimitClass
+		}{{"default", rl.Default}, {"crawl", rl.Crawl}, {"bypass", rl.Bypass}} {
+			if cl.c.RatePerMinute < 0 {
+				add("rateLimit."+cl.name+".ratePerMinute", "must not be negative (0 disables the limit)")
+			}
+			if cl.c.Burst < 0 {
+				add("rateLimit."+cl.name+".burst", "must not be negative")
+			}
+		}
+	}
+
+	if cc := c.CORS; cc.Enabled {
+		checkOrigins := func(field string, origins []string, creds bool) {
+			for _, o := range origins {
+				if strings.Count(o, "*") > 1 {
+					add(field, "%q: only one wildcard is supported", o)
+				}
+				if o == "*" && creds {
+					add(field, "\"*\" cannot be combined with allowCredentials")
+				}
+			}
+		}
+		checkOrigins("cors.allowedOrigins", cc.AllowedOrigins, cc.AllowCredentials)
+		if cc.MaxAge.Duration < 0 {
+			add("cors.maxAge", "must not be negative")
+		}
+		for i, o := range cc.Overrides {
+			field := fmt.Sprintf("cors.overrides[%d]", i)
+			if !strings.HasPrefix(o.Path, "/") {
+				add(field+".path", "must start with \"/\"")
+			}
+			if o.Path == "/admin" || strings.HasPrefix(o.Path, "/admin/") {
+				add(field+".path", "/admin is always same-origin only and cannot be overridden")
+			}
+			creds := cc.AllowCredentials
+			if o.AllowCredentials != nil {
+				creds = *o.AllowCredentials
+			}
+			checkOrigins(field+".allowedOrigins", o.AllowedOrigins, creds)
+		}
+	}
+
+	if c.Hive.Enabled {
+		if err := validateURL(c.Hive.BaseURL); err != nil {
+			add("hive.baseURL", "%v", err)
+		}
+		if c.Hive.Timeout.Duration <= 0 {
+			add("hive.timeout", "must be positive")
+		}
+		if c.Hive.CacheTTL.Duration < 0 {
+			add("hive.cacheTTL", "must not be negative")
+		}
+		if c.Hive.MaxConcurrency < 1 {
+			add("hive.maxConcurrency", "must be at least 1")
+		}
+		if c.Hive.PollInterval.Duration < 0 {
+			add("hive.pollInterval", "must not be negative (0 disables polling)")
+		}
+	}
+
+	if c.CubeCraft.Enabled {
+		c.CubeCraft.validate("cubecraft", add)
+	}
+
+	seen := map[string]bool{}
+	for i, n := range c.NotionBoards {
+		field := fmt.Sprintf("notionBoards[%d]", i)
+		switch {
+		case !sourceName.MatchString(n.Name):
+			add(field+".name", "must match %s", sourceName)
+		case reservedNames[n.Name]:
+			add(field+".name", "%q is reserved", n.Name)
+		case seen[n.Name]:
+			add(field+".name", "%q is used twice", n.Name)
+		}
+		seen[n.Name] = true
+		if n.Board == nil {
+			add(field+".board", "is required")
+		}
+		if n.Enabled {
+			n.validate(field, add)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var (
+	sourceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	// reservedNames are top-level paths and built-in sources a Notion board
+	// cannot be mounted over.
+	reservedNames = map[string]bool{
+		"hive": true, "cubecraft": true, "admin": true, "health": true, "livez": true,
+		"readyz": true, "metrics": true, "stats": true, "diff": true,
+	}
+)
+
+func (c CubeCraftConfig) validate(field string, add func(field, format string, args ...any)) {
+	if err := validateURL(c.APIURL); err != nil {
+		add(field+".apiURL", "%v", err)
+	}
+	if err := validateURL(c.SiteURL); err != nil {
+		add(field+".siteURL", "%v", err)
+	}
+	if c.CookieFile != "" && c.Cookie != "" {
+		add(field+".cookieFile", "must not be set together with cookie")
+	}
+	if c.CookieReloadInterval.Duration < 0 {
+		add(field+".cookieReloadInterval", "must not be negative (0 reloads only on SIGHUP)")
+	}
+	if strings.TrimSpace(c.ClientVersion) == "" {
+		add(field+".clientVersion", "must not be empty")
+	}
+	if c.Timeout.Duration <= 0 {
+		add(field+".timeout", "must be positive")
+	}
+	if c.CacheTTL.Duration < 0 {
+		add(field+".cacheTTL", "must not be negative")
+	}
+	if c.PollInterval.Duration < 0 {
+		add(field+".pollInterval", "must not be negative (0 disables polling)")
+	}
+	if c.ContentConcurrency < 0 {
+		add(field+".contentConcurrency", "must not be negative (0 skips page content)")
+	}
+	if b := c.Board; b != nil {
+		for _, f := range []struct{ key, value string }{
+			{"spaceId", b.SpaceID}, {"collectionId", b.CollectionID}, {"viewId", b.ViewID},
+		} {
+			if strings.TrimSpace(f.value) == "" {
+				add(field+".board."+f.key, "is required")
+			}
+		}
+		if b.GroupBy.empty() {
+			add(field+".board.groupBy", "needs a key or a name")
+		}
+		props := make([]string, 0, len(b.Properties))
+		for f := range b.Properties {
+			props = append(props, f)
+		}
+		sort.Strings(props)
+		for _, f := range props {
+			if b.Properties[f].empty() {
+				add(field+".board.properties."+f, "needs a key or a name")
+			}
+		}
+		if len(b.Columns) == 0 {
+			add(field+".board.columns", "must map at least one column")
+		}
+		cols := make([]string, 0, len(b.Columns))
+		for col := range b.Columns {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			switch {
+			case !sourceName.MatchString(col):
+				add(field+".board.columns", "column %q must match %s", col, sourceName)
+			case col == "columns" || col == "updates" || col == "snapshots" || col == "schema":
+				add(field+".board.columns", "column %q clashes with the /%s endpoint", col, col)
+			}
+			if len(b.Columns[col]) == 0 {
+				add(field+".board.columns."+col, "must list at least one value")
+			}
+		}
+		events := make([]string, 0, len(b.Events))
+		for e := range b.Events {
+			events = append(events, e)
+		}
+		sort.Strings(events)
+		for _, e := range events {
+			if !sourceName.MatchString(e) {
+				add(field+".board.events", "event %q must match %s", e, sourceName)
+			}
+		}
+	}
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must be an absolute http(s) URL, got %q", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host in %q", raw)
+	}
+	return nil
+}
+
+func validateIPOrPrefix(s string) error {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		_, err := netip.ParsePrefix(s)
+		return err
+	}
+	_, err := netip.ParseAddr(s)
+	return err
+}
+
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.CubeCraft.Cookie = out.CubeCraft.Cookie.redact()
+	out.NotionBoards = make([]NotionSourceConfig, len(c.NotionBoards))
+	for i, n := range c.NotionBoards {
+		n.Cookie = n.Cookie.redact()
+		out.NotionBoards[i] = n
+	}
+	out.Auth.AdminKey = out.Auth.AdminKey.redact()
+	if len(c.Tracing.Headers) > 0 {
+		out.Tracing.Headers = make([]string, len(c.Tracing.Headers))
+		for i, h := range c.Tracing.Headers {
+			k, _, _ := strings.Cut(h, "=")
+			out.Tracing.Headers[i] = k + "=" + string(Secret("x").redact())
+		}
+	}
+	return &out
+}
+
+func (c *Config) JSON() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\", got %s", string(b))
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+type Secret string
+
+func (s Secret) redact() Secret {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+func (s Secret) String() string { return string(s.redact()) }
+
+func (s Secret) Value() string { return string(s) }
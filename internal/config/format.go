@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML reads a single YAML document into plain maps and slices, so it
+// can go through the same strict JSON decoding as a .json file.
+func decodeYAML(data []byte) (any, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var extra yaml.Node
+	if err := dec.Decode(&extra); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("only one YAML document is allowed")
+	}
+	keepTimestamps(&doc)
+	var v any
+	if err := doc.Decode(&v); err != nil {
+		return nil, err
+	}
+	return stringKeys(v)
+}
+
+// keepTimestamps makes date-like scalars such as 2025-01-01 decode as the
+// string they were written as rather than a time.
+func keepTimestamps(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!timestamp" {
+		n.Tag = "!!str"
+	}
+	for _, c := range n.Content {
+		keepTimestamps(c)
+	}
+}
+
+func decodeTOML(data []byte) (any, error) {
+	var v map[string]any
+	if _, err := toml.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// stringKeys turns the map[any]any YAML produces for non-string keys, such
+// as a label for "2024", into map[string]any.
+func stringKeys(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			e, err := stringKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+		return v, nil
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			switch k.(type) {
+			case string, int, int64, uint64, float64, bool:
+			default:
+				return nil, fmt.Errorf("unsupported mapping key %v", k)
+			}
+			e, err := stringKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(k)] = e
+		}
+		return out, nil
+	case []any:
+		for i, e := range v {
+			e, err := stringKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+		return v, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const envPrefix = "ROADMAP_"
+
+type Flags struct {
+	ConfigPath  string
+	PrintConfig bool
+}
+
+type setFlags []string
+
+func (s *setFlags) String() string { return strings.Join(*s, ",") }
+
+func (s *setFlags) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func Load(name string, args []string) (*Config, Flags, error) {
+	var f Flags
+	var sets setFlags
+	var addr string
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&f.ConfigPath, "config", os.Getenv(envPrefix+"CONFIG"), "path to a YAML, TOML or JSON config file")
+	fs.BoolVar(&f.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+	fs.StringVar(&addr, "addr", "", "listen address, overrides server.addr")
+	fs.Var(&sets, "set", "override a config value, e.g. -set hive.cacheTTL=1m (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return nil, f, err
+	}
+
+	cfg := Default()
+	if f.ConfigPath != "" {
+		if err := loadFile(cfg, f.ConfigPath); err != nil {
+			return nil, f, fmt.Errorf("config file %s: %w", f.ConfigPath, err)
+		}
+	}
+	if err := applyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, f, err
+	}
+	if addr != "" {
+		cfg.Server.Addr = addr
+	}
+	for _, s := range sets {
+		path, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, f, fmt.Errorf("-set %q: expected key=value", s)
+		}
+		if err := Set(cfg, strings.TrimSpace(path), value); err != nil {
+			return nil, f, fmt.Errorf("-set %q: %w", s, err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, f, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, f, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var decode func([]byte) (any, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		decode = decodeYAML
+	case ".toml":
+		decode = decodeTOML
+	default:
+		return fmt.Errorf("unsupported config format %q (use .yaml, .yml, .toml or .json)", filepath.Ext(path))
+	}
+	if decode != nil {
+		v, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	if len(bytes.TrimSpace(data)) == 0 || string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(cfg)
+}
+
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	if port, ok := lookup("PORT"); ok && port != "" {
+		cfg.Server.Addr = ":" + port
+	}
+	if cookie, ok := lookup("NOTION_COOKIE"); ok && cookie != "" {
+		cfg.CubeCraft.Cookie = Secret(cookie)
+	}
+	var err error
+	walkLeaves(reflect.ValueOf(cfg).Elem(), nil, func(path []string, v reflect.Value) {
+		if err != nil {
+			return
+		}
+		name := envName(path)
+		raw, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if e := setValue(v, raw); e != nil {
+			err = fmt.Errorf("%s: %w", name, e)
+		}
+	})
+	return err
+}
+
+func envName(path []string) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = snakeUpper(p)
+	}
+	return envPrefix + strings.Join(parts, "_")
+}
+
+func snakeUpper(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rs[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func Set(cfg *Config, path, raw string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, seg := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("unknown key %q", path)
+		}
+		f, ok := fieldByTag(v, seg)
+		if !ok {
+			return fmt.Errorf("unknown key %q", path)
+		}
+		v = f
+	}
+	if !isLeaf(v) {
+		return fmt.Errorf("%q is not a scalar value", path)
+	}
+	return setValue(v, raw)
+}
+
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(jsonName(t.Field(i)), name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func jsonName(f reflect.StructField) string {
+	tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if tag == "" {
+		return f.Name
+	}
+	return tag
+}
+
+var durationType = reflect.TypeOf(Duration{})
+
+func isLeaf(v reflect.Value) bool {
+	if v.Type() == durationType {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+func walkLeaves(v reflect.Value, path []string, fn func([]string, reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("json") == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		p := append(append([]string(nil), path...), jsonName(sf))
+		switch {
+		case isLeaf(fv):
+			fn(p, fv)
+		case fv.Kind() == reflect.Struct:
+			walkLeaves(fv, p, fn)
+		}
+	}
+}
+
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(Duration{d}))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(i)
+	case reflect.Float64:
+		fl, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(fl)
+	case reflect.Slice:
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func load(t *testing.T, name, content string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Default()
+	return cfg, loadFile(cfg, path)
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "yaml nesting and comments",
+			file: "c.yaml",
+			content: `
+# top comment
+server:
+  addr: ":9090" # trailing comment
+  tls:
+    certFile: "/etc/tls/cert.pem#1"
+hive:
+  timeout: 5s
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.Server.Addr != ":9090" || c.Server.TLS.CertFile != "/etc/tls/cert.pem#1" || c.Hive.Timeout.Duration != 5*time.Second {
+					t.Errorf("got addr %q, certFile %q, timeout %s", c.Server.Addr, c.Server.TLS.CertFile, c.Hive.Timeout)
+				}
+				if c.Hive.CacheTTL != Default().Hive.CacheTTL {
+					t.Errorf("unset cacheTTL changed to %s", c.Hive.CacheTTL)
+				}
+			},
+		},
+		{
+			name: "yaml quoting",
+			file: "c.yml",
+			content: `
+cubecraft:
+  clientVersion: '23.13'
+  cookie: "a=b; c=\"d\""
+  siteURL: 'it''s: here'
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.CubeCraft.ClientVersion != "23.13" || c.CubeCraft.Cookie != `a=b; c="d"` || c.CubeCraft.SiteURL != "it's: here" {
+					t.Errorf("got %q, %q, %q", c.CubeCraft.ClientVersion, c.CubeCraft.Cookie, c.CubeCraft.SiteURL)
+				}
+			},
+		},
+		{
+			name: "yaml flow collections and sequences of mappings",
+			file: "c.yaml",
+			content: `
+cors:
+  allowedMethods: [GET, "POST"]
+  overrides:
+    - path: /cubecraft
+      allowedOrigins: ["https://a.example.com", https://b.example.com]
+    - {path: /metrics, sameOrigin: true}
+`,
+			check: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c.CORS.AllowedMethods, []string{"GET", "POST"}) {
+					t.Errorf("allowedMethods = %q", c.CORS.AllowedMethods)
+				}
+				want := []CORSOverride{
+					{Path: "/cubecraft", AllowedOrigins: []string{"https://a.example.com", "https://b.example.com"}},
+					{Path: "/metrics", SameOrigin: true},
+				}
+				if !reflect.DeepEqual(c.CORS.Overrides, want) {
+					t.Errorf("overrides = %+v", c.CORS.Overrides)
+				}
+			},
+		},
+		{
+			name: "yaml non-string keys and date-like values",
+			file: "c.yaml",
+			content: `
+cubecraft:
+  board:
+    labels:
+      2024: Last year
+      Released: 2025-01-01
+`,
+			check: func(t *testing.T, c *Config) {
+				want := map[string]string{"2024": "Last year", "Released": "2025-01-01"}
+				if c.CubeCraft.Board == nil || !reflect.DeepEqual(c.CubeCraft.Board.Labels, want) {
+					t.Errorf("labels = %+v", c.CubeCraft.Board)
+				}
+			},
+		},
+		{
+			name:    "empty yaml keeps defaults",
+			file:    "c.yaml",
+			content: "# nothing here\n",
+			check: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c, Default()) {
+					t.Error("empty file changed the defaults")
+				}
+			},
+		},
+		{
+			name: "toml",
+			file: "c.toml",
+			content: `
+[server]
+addr = ":9090"
+
+[hive]
+timeout = "5s"
+
+[cors]
+allowedMethods = ["GET", "POST"]
+
+[[cors.overrides]]
+path = "/metrics"
+sameOrigin = true
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.Server.Addr != ":9090" || c.Hive.Timeout.Duration != 5*time.Second {
+					t.Errorf("got addr %q, timeout %s", c.Server.Addr, c.Hive.Timeout)
+				}
+				if len(c.CORS.Overrides) != 1 || !c.CORS.Overrides[0].SameOrigin {
+					t.Errorf("overrides = %+v", c.CORS.Overrides)
+				}
+			},
+		},
+		{
+			name:    "json",
+			file:    "c.json",
+			content: `{"server": {"addr": ":9090"}}`,
+			check: func(t *testing.T, c *Config) {
+				if c.Server.Addr != ":9090" {
+					t.Errorf("addr = %q", c.Server.Addr)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := load(t, tt.file, tt.content)
+			if err != nil {
+				t.Fatalf("loadFile: %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestLoadFileRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"unknown field", "c.yaml", "server:\n  adress: \":80\"\n", "unknown field"},
+		{"unknown toml field", "c.toml", "[server]\nadress = \":80\"\n", "unknown field"},
+		{"duplicate key", "c.yaml", "server:\n  addr: a\n  addr: b\n", "already defined"},
+		{"tab indentation", "c.yaml", "server:\n\taddr: a\n", "line 2"},
+		{"several documents", "c.yaml", "server: {}\n---\nhive: {}\n", "one YAML document"},
+		{"wrong type", "c.yaml", "hive:\n  timeout: 5\n", "duration must be a string"},
+		{"scalar for a mapping", "c.yaml", "server: on\n", "cannot unmarshal"},
+		{"broken toml", "c.toml", "[server\naddr = 1\n", "toml"},
+		{"unsupported extension", "c.ini", "addr=1\n", "unsupported config format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.file, tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadFile error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	cfg := Default()
+	if err := loadFile(cfg, "../../config.example.yaml"); err != nil {
+		t.Fatalf("config.example.yaml: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("config.example.yaml: %v", err)
+	}
+}
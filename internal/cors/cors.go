@@ -0,0 +1,263 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"roadmapapi/internal/problem"
+)
+
+type Policy struct {
+	// AllowedOrigins holds exact origins, "*", or patterns with a single
+	// wildcard such as "https://*.example.com".
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// Override replaces the policy for every path under Path. SameOrigin turns
+// CORS off for those paths and rejects cross-origin requests outright.
+type Override struct {
+	Path       string
+	SameOrigin bool
+	Policy     Policy
+}
+
+type policy struct {
+	Policy
+	sameOrigin bool
+	anyOrigin  bool
+	anyHeader  bool
+	origins    []pattern
+	methods    []string
+	headers    []string
+	exposed    string
+	maxAge     string
+}
+
+type pattern struct {
+	prefix, suffix string
+	wildcard       bool
+}
+
+func (p pattern) match(origin string) bool {
+	if !p.wildcard {
+		return origin == p.prefix
+	}
+	return len(origin) > len(p.prefix)+len(p.suffix) &&
+		strings.HasPrefix(origin, p.prefix) && strings.HasSuffix(origin, p.suffix)
+}
+
+func compile(p Policy, sameOrigin bool) (*policy, error) {
+	c := &policy{Policy: p, sameOrigin: sameOrigin}
+	if sameOrigin {
+		return c, nil
+	}
+	for _, o := range p.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		switch strings.Count(o, "*") {
+		case 0:
+			c.origins = append(c.origins, pattern{prefix: o})
+		case 1:
+			if o == "*" {
+				c.anyOrigin = true
+				continue
+			}
+			pre, suf, _ := strings.Cut(o, "*")
+			c.origins = append(c.origins, pattern{prefix: pre, suffix: suf, wildcard: true})
+		default:
+			return nil, fmt.Errorf("origin %q: only one wildcard is supported", o)
+		}
+	}
+	if c.anyOrigin && p.AllowCredentials {
+		return nil, fmt.Errorf("allowed origin \"*\" cannot be combined with credentials")
+	}
+	for _, m := range p.AllowedMethods {
+		c.methods = append(c.methods, strings.ToUpper(strings.TrimSpace(m)))
+	}
+	for _, h := range p.AllowedHeaders {
+		h = http.CanonicalHeaderKey(strings.TrimSpace(h))
+		if h == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers = append(c.headers, h)
+	}
+	c.exposed = strings.Join(p.ExposedHeaders, ", ")
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return c, nil
+}
+
+func (c *policy) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, p := range c.origins {
+		if p.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+type route struct {
+	path string
+	p    *policy
+}
+
+type CORS struct {
+	def        *policy
+	overrides  []route
+	trustProxy bool
+}
+
+// New builds the middleware. trustProxy lets X-Forwarded-Proto decide the
+// request scheme in same-origin checks; only set it behind a proxy that
+// sets the header.
+func New(def Policy, trustProxy bool, overrides ...Override) (*CORS, error) {
+	d, err := compile(def, false)
+	if err != nil {
+		return nil, err
+	}
+	c := &CORS{def: d, trustProxy: trustProxy}
+	for _, o := range overrides {
+		p, err := compile(o.Policy, o.SameOrigin)
+		if err != nil {
+			return nil, fmt.Errorf("override %s: %w", o.Path, err)
+		}
+		c.overrides = append(c.overrides, route{strings.TrimSuffix(o.Path, "/"), p})
+	}
+	// Longest path first so the most specific override wins.
+	sort.SliceStable(c.overrides, func(i, j int) bool {
+		return len(c.overrides[i].path) > len(c.overrides[j].path)
+	})
+	return c, nil
+}
+
+func (c *CORS) policyFor(path string) *policy {
+	for _, o := range c.overrides {
+		if path == o.path || strings.HasPrefix(path, o.path+"/") {
+			return o.p
+		}
+	}
+	return c.def
+}
+
+// Handler answers preflight requests itself, since routes only register
+// their real methods, and adds CORS headers to allowed cross-origin
+// requests. A nil CORS disables the middleware.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		p := c.policyFor(r.URL.Path)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if p.sameOrigin {
+			if origin != "" && !c.sameOrigin(origin, r) {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeCORSRejected, "cross-origin requests are not allowed on this endpoint"))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !p.allowOrigin(origin) {
+			if preflight {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeCORSRejected, "origin not allowed"))
+				return
+			}
+			// Without CORS headers the browser withholds the response.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if preflight {
+			c.preflight(w, r, p, origin)
+			return
+		}
+		p.setOrigin(h, origin)
+		if p.exposed != "" {
+			h.Set("Access-Control-Expose-Headers", p.exposed)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, p *policy, origin string) {
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !slices.Contains(p.methods, method) {
+		problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeCORSRejected, fmt.Sprintf("method %s not allowed", method)))
+		return
+	}
+	var requested []string
+	for _, f := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if f = http.CanonicalHeaderKey(strings.TrimSpace(f)); f != "" {
+			if !p.anyHeader && !slices.Contains(p.headers, f) {
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeCORSRejected, fmt.Sprintf("header %s not allowed", f)))
+				return
+			}
+			requested = append(requested, f)
+		}
+	}
+
+	p.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	if len(requested) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *policy) setOrigin(h http.Header, origin string) {
+	if p.anyOrigin && !p.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) sameOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if fp := r.Header.Get("X-Forwarded-Proto"); fp != "" && c.trustProxy {
+		scheme = fp
+	}
+	return strings.EqualFold(u.Scheme, scheme) && strings.EqualFold(u.Host, r.Host)
+}
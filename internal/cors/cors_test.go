@@ -0,0 +1,46 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSameOriginForwardedProto(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		origin     string
+		proto      string
+		want       int
+	}{
+		{"plain http", false, "http://api.example.com", "", http.StatusOK},
+		{"scheme mismatch", false, "https://api.example.com", "", http.StatusForbidden},
+		{"untrusted forwarded proto is ignored", false, "https://api.example.com", "https", http.StatusForbidden},
+		{"untrusted forwarded proto keeps the connection scheme", false, "http://api.example.com", "https", http.StatusOK},
+		{"trusted forwarded proto", true, "https://api.example.com", "https", http.StatusOK},
+		{"trusted forwarded proto mismatch", true, "http://api.example.com", "https", http.StatusForbidden},
+		{"other host", true, "https://evil.example.com", "https", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(Policy{}, tt.trustProxy, Override{Path: "/admin", SameOrigin: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "http://api.example.com/admin/keys", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
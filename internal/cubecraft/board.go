@@ -0,0 +1,339 @@
+package cubecraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Board maps a public Notion board onto roadmap columns. Properties are
+// looked up by name in the collection schema, falling back to their
+// configured keys when the name is not found.
+type Board struct {
+	SpaceID      string
+	CollectionID string
+	ViewID       string
+	// GroupBy is the select property whose values form the board's groups.
+	GroupBy Property
+	// Groups lists the board groups in board order, as Notion expects them
+	// in queries. When empty it is derived from Columns.
+	Groups []BoardGroup
+	// Columns maps each roadmap column to the GroupBy values it shows.
+	Columns map[string][]string
+	// Labels renames GroupBy values for display; unlisted values are shown
+	// as they are.
+	Labels map[string]string
+	// Events names the change events for cards moving into the listed
+	// GroupBy values; other moves are "status_change".
+	Events map[string][]string
+	// Properties maps item fields (category, network, projectLead,
+	// releasedAt, releasePost) to board properties.
+	Properties map[string]Property
+	// SortBy is the field or property key cards are sorted by, descending.
+	SortBy   string
+	TimeZone string
+}
+
+type BoardGroup struct {
+	Value  string
+	Hidden bool
+}
+
+// Property identifies a board property. Name is preferred since Notion keeps
+// it when a property is recreated; Key is used when no property has that
+// name. Type, if set, is the Notion type the property must have.
+type Property struct {
+	Key  string
+	Name string
+	Type string
+}
+
+// statusField is the field the GroupBy property fills.
+const statusField = "status"
+
+// releasedColumn is the column every source shows shipped items in.
+const releasedColumn = "released"
+
+// DefaultBoard is the CubeCraft roadmap.
+func DefaultBoard() Board {
+	return Board{
+		SpaceID:      "2a7d9973-2a91-430b-9d0f-520163f17777",
+		CollectionID: "d14e867c-526a-4627-ad4f-1f56fdee77d6",
+		ViewID:       "79bd3042-c1cf-42aa-9d4e-d81a3043c505",
+		GroupBy:      Property{Key: "3E6J", Name: "Status", Type: "select"},
+		Groups: []BoardGroup{
+			{Value: "Information"},
+			{Value: "In Progress"},
+			{Value: "Testing"},
+			{Value: "Released"},
+			{Value: "Scrapped", Hidden: true},
+			{Value: "BLOCKED", Hidden: true},
+		},
+		Columns: map[string][]string{
+			"in-progress": {"In Progress"},
+			"coming-next": {"Testing"},
+			"released":    {"Released"},
+		},
+		Labels: map[string]string{
+			"Testing": "Coming Next...",
+		},
+		Events: map[string][]string{
+			"scrapped": {"Scrapped"},
+			"blocked":  {"BLOCKED"},
+		},
+		Properties: map[string]Property{
+			"network":     {Key: "@@W>", Name: "Network"},
+			"category":    {Key: "K:rY", Name: "Category"},
+			"projectLead": {Key: `\wxR`, Name: "Project Lead"},
+			"releasePost": {Key: "^NHI", Name: "Release Post"},
+			"releasedAt":  {Key: "?igY", Name: "Release Date", Type: "date"},
+		},
+		SortBy:   "releasedAt",
+		TimeZone: "Europe/Berlin",
+	}
+}
+
+// ColumnNames returns the board's columns, sorted.
+func (b Board) ColumnNames() []string {
+	out := make([]string, 0, len(b.Columns))
+	for c := range b.Columns {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NativeColumns maps a column for each board group, named after the group,
+// to its GroupBy value.
+func (b Board) NativeColumns() map[string]string {
+	out := make(map[string]string)
+	for _, g := range b.groups() {
+		if s := slug(g.Value); s != "" {
+			out[s] = g.Value
+		}
+	}
+	return out
+}
+
+// column returns the GroupBy values a column shows. Mapped columns take
+// precedence over native ones of the same name.
+func (b Board) column(name string) ([]string, bool) {
+	if values, ok := b.Columns[name]; ok {
+		return values, true
+	}
+	if v, ok := b.NativeColumns()[name]; ok {
+		return []string{v}, true
+	}
+	return nil, false
+}
+
+// PollColumns returns the mapped columns and the native columns of groups
+// no mapped column shows, so that together they cover every card once.
+func (b Board) PollColumns() []string {
+	out := b.ColumnNames()
+	mapped := make(map[string]bool)
+	for _, values := range b.Columns {
+		for _, v := range values {
+			mapped[v] = true
+		}
+	}
+	native := b.NativeColumns()
+	extra := make([]string, 0, len(native))
+	for col, v := range native {
+		if _, ok := b.Columns[col]; !ok && !mapped[v] {
+			extra = append(extra, col)
+		}
+	}
+	sort.Strings(extra)
+	return append(out, extra...)
+}
+
+func (b Board) columnError() error {
+	native := make([]string, 0)
+	for col := range b.NativeColumns() {
+		if _, ok := b.Columns[col]; !ok {
+			native = append(native, col)
+		}
+	}
+	sort.Strings(native)
+	return fmt.Errorf("column must be one of [%s] or a board column [%s]",
+		strings.Join(b.ColumnNames(), ", "), strings.Join(native, ", "))
+}
+
+// event names the change event for a card moving to the GroupBy value to.
+func (b Board) event(to string) string {
+	names := make([]string, 0, len(b.Events))
+	for e := range b.Events {
+		names = append(names, e)
+	}
+	sort.Strings(names)
+	for _, e := range names {
+		if slices.Contains(b.Events[e], to) {
+			return e
+		}
+	}
+	return "status_change"
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(s string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// released reports whether status, a displayed GroupBy value, is one the
+// released column shows.
+func (b Board) released(status string) bool {
+	values, _ := b.column(releasedColumn)
+	for _, v := range values {
+		if strings.EqualFold(b.Label(v), status) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFields are the orders Service.All supports, by lowercased field.
+var sortFields = []string{"releasedat", "lastupdated", "createdat", "title"}
+
+// sortOrder validates a requested sort. Without one, the released column is
+// sorted by the board's SortBy when that is a supported field, other mapped
+// columns by last update and native columns by title.
+func (b Board) sortOrder(in, column string) string {
+	in = strings.ToLower(strings.TrimSpace(in))
+	if field, dir, ok := strings.Cut(in, ":"); ok && slices.Contains(sortFields, field) && (dir == "asc" || dir == "desc") {
+		return in
+	}
+	column = strings.ToLower(column)
+	if sortBy := strings.ToLower(b.SortBy); column == releasedColumn && slices.Contains(sortFields, sortBy) {
+		return sortBy + ":desc"
+	}
+	if _, ok := b.Columns[column]; ok {
+		return "lastupdated:desc"
+	}
+	return "title:asc"
+}
+
+// Label returns how a GroupBy value is displayed.
+func (b Board) Label(value string) string {
+	if l, ok := b.Labels[value]; ok {
+		return l
+	}
+	return value
+}
+
+// fields returns every mapped property by field, including the GroupBy
+// property as "status".
+func (b Board) fields() map[string]Property {
+	out := make(map[string]Property, len(b.Properties)+1)
+	for f, p := range b.Properties {
+		out[f] = p
+	}
+	out[statusField] = b.GroupBy
+	return out
+}
+
+// staticKeys maps fields to their configured keys, for use before the
+// schema has been read.
+func (b Board) staticKeys() map[string]string {
+	out := make(map[string]string)
+	for f, p := range b.fields() {
+		if p.Key != "" {
+			out[f] = p.Key
+		}
+	}
+	return out
+}
+
+// rename keys props by the fields they fill, given the field to key mapping
+// in effect, and the remaining properties by their schema names. The title
+// is left out.
+func rename(props map[string]PropertyValue, keys map[string]string, live map[string]SchemaProperty) map[string]PropertyValue {
+	names := make(map[string]string, len(keys))
+	for f, k := range keys {
+		names[k] = f
+	}
+	out := make(map[string]PropertyValue, len(props))
+	for k, v := range props {
+		switch {
+		case k == "title":
+		case names[k] != "":
+			out[names[k]] = v
+		case live[k].Name != "":
+			out[live[k].Name] = v
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (b Board) groups() []BoardGroup {
+	if len(b.Groups) > 0 {
+		return b.Groups
+	}
+	var out []BoardGroup
+	for _, col := range b.ColumnNames() {
+		for _, v := range b.Columns[col] {
+			out = append(out, BoardGroup{Value: v})
+		}
+	}
+	return out
+}
+
+// queryPayload builds a queryCollection request for the board, asking for up
+// to limit cards per group. keys maps fields to the property keys in effect.
+func (b Board) queryPayload(keys map[string]string, limit int) ([]byte, error) {
+	groupBy := keys[statusField]
+	if groupBy == "" {
+		return nil, fmt.Errorf("cubecraft: group-by property %q not found", b.GroupBy.Name)
+	}
+	type selectValue struct {
+		Type  string `json:"type"`
+		Value string `json:"value,omitempty"`
+	}
+	type groupPref struct {
+		Value    selectValue `json:"value"`
+		Hidden   bool        `json:"hidden"`
+		Property string      `json:"property"`
+	}
+	prefs := make([]groupPref, 0, len(b.groups())+1)
+	for _, g := range b.groups() {
+		prefs = append(prefs, groupPref{Value: selectValue{Type: "select", Value: g.Value}, Hidden: g.Hidden, Property: groupBy})
+	}
+	// Cards with no value form a group of their own.
+	prefs = append(prefs, groupPref{Value: selectValue{Type: "select"}, Hidden: true, Property: groupBy})
+
+	sorts := []map[string]string{}
+	sortBy := b.SortBy
+	if k, ok := keys[sortBy]; ok {
+		sortBy = k
+	}
+	if sortBy != "" {
+		sorts = append(sorts, map[string]string{"property": sortBy, "direction": "descending"})
+	}
+	return json.Marshal(map[string]any{
+		"source":         map[string]string{"type": "collection", "id": b.CollectionID, "spaceId": b.SpaceID},
+		"collectionView": map[string]string{"id": b.ViewID, "spaceId": b.SpaceID},
+		"loader": map[string]any{
+			"reducers": map[string]any{
+				"board_columns": map[string]any{
+					"type":                "groups",
+					"version":             "v2",
+					"returnPinnedGroups":  true,
+					"groupBy":             map[string]any{"sort": map[string]string{"type": "manual"}, "type": "select", "property": groupBy},
+					"groupSortPreference": prefs,
+					"limit":               limit,
+					"aggregation":         map[string]any{"type": "independent", "groupAggregation": map[string]string{"aggregator": "count"}},
+					"blockResults":        map[string]any{"type": "independent", "defaultLimit": limit, "loadContentCover": false, "groupOverrides": map[string]any{}},
+				},
+			},
+			"sort":         sorts,
+			"searchQuery":  "",
+			"userTimeZone": b.TimeZone,
+		},
+	})
+}
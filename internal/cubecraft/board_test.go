@@ -0,0 +1,61 @@
+package cubecraft
+
+import "testing"
+
+func TestReleased(t *testing.T) {
+	custom := Board{
+		Columns: map[string][]string{"released": {"Shipped", "Live"}, "doing": {"Doing"}},
+		Labels:  map[string]string{"Live": "Live now"},
+	}
+	tests := []struct {
+		name   string
+		board  Board
+		status string
+		want   bool
+	}{
+		{"default board", DefaultBoard(), "Released", true},
+		{"default board, other column", DefaultBoard(), "Coming Next...", false},
+		{"custom label", custom, "shipped", true},
+		{"relabelled value", custom, "Live now", true},
+		{"raw value of a relabelled group", custom, "Live", false},
+		{"other column", custom, "Doing", false},
+		{"CubeCraft label on a custom board", custom, "Released", false},
+		{"no released column", Board{Columns: map[string][]string{"doing": {"Doing"}}}, "Released", false},
+	}
+	for _, tt := range tests {
+		if got := tt.board.released(tt.status); got != tt.want {
+			t.Errorf("%s: released(%q) = %v, want %v", tt.name, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestSortOrder(t *testing.T) {
+	custom := Board{
+		Columns: map[string][]string{"released": {"Shipped"}, "doing": {"Doing"}},
+		Groups:  []BoardGroup{{Value: "Doing"}, {Value: "Shipped"}, {Value: "Ideas"}},
+		SortBy:  "createdAt",
+	}
+	tests := []struct {
+		name   string
+		board  Board
+		in     string
+		column string
+		want   string
+	}{
+		{"explicit order", DefaultBoard(), "Title:ASC", "released", "title:asc"},
+		{"unknown order falls back", DefaultBoard(), "upvotes:desc", "released", "releasedat:desc"},
+		{"missing direction falls back", DefaultBoard(), "title", "in-progress", "lastupdated:desc"},
+		{"default board released", DefaultBoard(), "", "released", "releasedat:desc"},
+		{"default board mapped column", DefaultBoard(), "", "coming-next", "lastupdated:desc"},
+		{"default board native column", DefaultBoard(), "", "scrapped", "title:asc"},
+		{"custom board released uses SortBy", custom, "", "released", "createdat:desc"},
+		{"custom board mapped column", custom, "", "doing", "lastupdated:desc"},
+		{"custom board native column", custom, "", "ideas", "title:asc"},
+		{"SortBy that is a property key", Board{Columns: map[string][]string{"released": {"Shipped"}}, SortBy: "?igY"}, "", "released", "lastupdated:desc"},
+	}
+	for _, tt := range tests {
+		if got := tt.board.sortOrder(tt.in, tt.column); got != tt.want {
+			t.Errorf("%s: sortOrder(%q, %q) = %q, want %q", tt.name, tt.in, tt.column, got, tt.want)
+		}
+	}
+}
@@ -6,64 +6,67 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/tracing"
+	"roadmapapi/internal/upstream"
 )
 
 const (
-	notionAPIURL      = "https://cubecraft.notion.site/api/v3/queryCollection?src=initial_load"
-	notionSiteBaseURL = "https://cubecraft.notion.site/e86c96a3ee78465d8e5c24c22489c094"
-	collectionViewID  = "79bd3042-c1cf-42aa-9d4e-d81a3043c505"
-	clientTimeout     = 30 * time.Second
-	defaultPageSize   = 10
+	DefaultAPIURL   = "https://cubecraft.notion.site/api/v3"
+	DefaultSiteURL  = "https://cubecraft.notion.site/e86c96a3ee78465d8e5c24c22489c094"
+	clientTimeout   = 30 * time.Second
+	defaultPageSize = 10
 )
 
-var notionQueryPayload = []byte(`{
-  "source": {
-    "type": "collection",
-    "id": "d14e867c-526a-4627-ad4f-1f56fdee77d6",
-    "spaceId": "2a7d9973-2a91-430b-9d0f-520163f17777"
-  },
-  "collectionView": {
-    "id": "79bd3042-c1cf-42aa-9d4e-d81a3043c505",
-    "spaceId": "2a7d9973-2a91-430b-9d0f-520163f17777"
-  },
-  "loader": {
-    "reducers": {
-      "board_columns": {
-        "type": "groups",
-        "version": "v2",
-        "returnPinnedGroups": true,
-        "groupBy": { "sort": { "type": "manual" }, "type": "select", "property": "3E6J" },
-        "groupSortPreference": [
-          { "value": { "type": "select", "value": "Information" }, "hidden": false, "property": "3E6J" },
-          { "value": { "type": "select", "value": "In Progress" }, "hidden": false, "property": "3E6J" },
-          { "value": { "type": "select", "value": "Testing" }, "hidden": false, "property": "3E6J" },
-          { "value": { "type": "select", "value": "Released" }, "hidden": false, "property": "3E6J" },
-          { "value": { "type": "select", "value": "Scrapped" }, "hidden": true,  "property": "3E6J" },
-          { "value": { "type": "select", "value": "BLOCKED" },  "hidden": true,  "property": "3E6J" },
-          { "value": { "type": "select" }, "hidden": true, "property": "3E6J" }
-        ],
-        "limit": 10,
-        "aggregation": { "type": "independent", "groupAggregation": { "aggregator": "count" } },
-        "blockResults": { "type": "independent", "defaultLimit": 50, "loadContentCover": false, "groupOverrides": {} }
-      }
-    },
-    "sort": [ { "property": "?igY", "direction": "descending" } ],
-    "searchQuery": "",
-    "userTimeZone": "Europe/Berlin"
-  }
-}`)
-
 type ClientOption func(*Client)
 
 func WithCacheTTL(ttl time.Duration) ClientOption {
 	return func(c *Client) { c.cacheTTL = ttl }
 }
 
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+func WithAPIURL(u string) ClientOption {
+	return func(c *Client) { c.apiURL = strings.TrimRight(u, "/") }
+}
+
+func WithSiteURL(u string) ClientOption {
+	return func(c *Client) { c.siteURL = strings.TrimRight(u, "/") }
+}
+
+// WithCookie sends a fixed cookie; use WithSession for one that can be
+// reloaded.
+func WithCookie(cookie string) ClientOption {
+	return func(c *Client) { c.session, _ = NewSession(SessionOptions{Cookie: cookie}) }
+}
+
+func WithSession(s *Session) ClientOption {
+	return func(c *Client) { c.session = s }
+}
+
+func WithLogger(l *slog.Logger) ClientOption {
+	return func(c *Client) { c.logger = l }
+}
+
+// WithBoard sets the Notion board to load; the default is DefaultBoard.
+func WithBoard(b Board) ClientOption {
+	return func(c *Client) { c.board = b }
+}
+
+// WithSourceName sets the name the board is reported under in metrics, logs
+// and responses; the default is "cubecraft".
+func WithSourceName(name string) ClientOption {
+	return func(c *Client) { c.source = name }
+}
+
 type cacheEntry struct {
 	data      []Card
 	expiresAt time.Time
@@ -71,64 +74,228 @@ type cacheEntry struct {
 
 type Client struct {
 	httpClient *http.Client
+	apiURL     string
+	siteURL    string
+	session    *Session
+	board      Board
+	source     string
 	cacheTTL   time.Duration
+	logger     *slog.Logger
 	mu         sync.RWMutex
 	cache      *cacheEntry
+	truncation *Truncation
+	schema     *Schema
+	// seenTypes is the type each field's property had when first read.
+	seenTypes map[string]string
+
+	contentConcurrency int
+	contentMu          sync.Mutex
+	contents           map[string]contentEntry
 }
 
 func NewClient(opts ...ClientOption) *Client {
 	c := &Client{
 		httpClient: &http.Client{Timeout: clientTimeout},
+		apiURL:     DefaultAPIURL,
+		siteURL:    DefaultSiteURL,
+		board:      DefaultBoard(),
+		source:     "cubecraft",
 		cacheTTL:   0,
+		logger:     slog.Default(),
+		seenTypes:  make(map[string]string),
+		contents:   make(map[string]contentEntry),
 	}
 	for _, o := range opts {
 		o(c)
 	}
+	if c.session == nil {
+		c.session, _ = NewSession(SessionOptions{Logger: c.logger})
+	}
 	return c
 }
 
-func (c *Client) Fetch(ctx context.Context) ([]Card, error) {
+// Name is the source name the board is served under.
+func (c *Client) Name() string { return c.source }
+
+func (c *Client) Board() Board { return c.board }
+
+func (c *Client) Session() *Session { return c.session }
+
+func (c *Client) queryURL() string {
+	return c.apiURL + "/queryCollection?src=initial_load"
+}
+
+func (c *Client) syncURL() string {
+	return c.apiURL + "/syncRecordValues"
+}
+
+// PurgeCache drops the cached board when url is empty or matches the query
+// URL, and returns how many entries were removed.
+func (c *Client) PurgeCache(url string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil || (url != "" && url != c.queryURL()) {
+		return 0
+	}
+	c.cache = nil
+	return 1
+}
+
+func (c *Client) CachedURLs() map[string]time.Time {
+	out := make(map[string]time.Time)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cache != nil {
+		out[c.queryURL()] = c.cache.expiresAt
+	}
+	return out
+}
+
+func (c *Client) observe(ctx context.Context, endpoint, u string, status int, d time.Duration, err error) {
+	metrics.ObserveUpstream(c.source, endpoint, status, d)
+	level := slog.LevelDebug
+	if err != nil || status >= 400 {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("source", c.source),
+		slog.String("url", u),
+		slog.Int("status", status),
+		slog.Duration("duration", d),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(ctx, level, "upstream request", attrs...)
+}
+
+func (c *Client) Fetch(ctx context.Context) (_ []Card, err error) {
+	ctx, span := tracing.Start(ctx, "cubecraft.fetch", tracing.String("source", c.source))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	if c.cacheTTL > 0 {
 		c.mu.RLock()
 		if c.cache != nil && time.Now().Before(c.cache.expiresAt) {
 			data := c.cache.data
 			c.mu.RUnlock()
+			metrics.CacheHits.With(c.source).Inc()
+			span.SetAttrs(tracing.String("cache", "hit"), tracing.Int("cubecraft.cards", len(data)))
 			return data, nil
 		}
+		expired := c.cache != nil
 		c.mu.RUnlock()
+		if expired {
+			metrics.CacheEvictions.With(c.source).Inc()
+		}
+		metrics.CacheMisses.With(c.source).Inc()
+		span.SetAttrs(tracing.String("cache", "miss"))
+	} else {
+		span.SetAttrs(tracing.String("cache", "disabled"))
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notionAPIURL, bytes.NewReader(notionQueryPayload))
+	res, trunc, err := c.load(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Cookie", os.Getenv("NOTION_COOKIE"))
-	req.Header.Set("x-notion-space-id", "2a7d9973-2a91-430b-9d0f-520163f17777")
-	req.Header.Set("x-notion-active-user-header", "")
-	req.Header.Set("notion-client-version", "23.13.0.5155")
-	req.Header.Set("notion-audit-log-platform", "web")
 
+	_, decode := tracing.Start(ctx, "cubecraft.decode_cards", tracing.Int("blocks", len(res.blocks)))
+	cards := c.decodeCards(res.blocks, res.users)
+	decode.End()
+	c.mu.Lock()
+	c.truncation = trunc
+	c.mu.Unlock()
+	if trunc == nil {
+		// Only a complete load shows which cards are gone.
+		c.pruneContents(res)
+	}
+	if trunc != nil {
+		span.SetAttrs(tracing.Int("cubecraft.missing_cards", trunc.Missing))
+	}
+	span.SetAttrs(tracing.Int("cubecraft.cards", len(cards)))
+
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.cache = &cacheEntry{data: cards, expiresAt: time.Now().Add(c.cacheTTL)}
+		c.mu.Unlock()
+	}
+	return cards, nil
+}
+
+// post sends a JSON request to a Notion API endpoint and returns the body.
+// Error statuses and error bodies are returned as *upstream.Error.
+func (c *Client) post(ctx context.Context, endpoint, u string, body []byte) ([]byte, error) {
+	ctx, span := tracing.StartKind(ctx, "POST notion "+endpoint, tracing.KindClient,
+		tracing.String("http.request.method", http.MethodPost),
+		tracing.String("url.full", u),
+	)
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	tracing.Inject(ctx, req.Header)
+	c.session.apply(req, c.board.SpaceID)
+
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.observe(ctx, endpoint, req.URL.String(), 0, time.Since(start), err)
+		err = upstream.FromTransport(c.source, endpoint, err)
+		span.RecordError(err)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.observe(ctx, endpoint, req.URL.String(), resp.StatusCode, time.Since(start), nil)
+	span.SetAttrs(tracing.Int("http.response.status_code", resp.StatusCode))
 
 	b, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
 	if err != nil {
+		err = upstream.FromTransport(c.source, endpoint, err)
+		span.RecordError(err)
 		return nil, err
 	}
+	span.SetAttrs(tracing.Int("http.response.body.size", len(b)))
+	if e := c.responseError(endpoint, resp, b); e != nil {
+		c.session.record(e)
+		span.RecordError(e)
+		return nil, e
+	}
+	return b, nil
+}
 
-	var full struct {
-		RecordMap struct {
-			Block map[string]json.RawMessage `json:"block"`
-		} `json:"recordMap"`
+// responseError classifies an error status, or an error body Notion sent
+// with a 200.
+func (c *Client) responseError(endpoint string, resp *http.Response, b []byte) *upstream.Error {
+	var nerr struct {
+		ErrorID string `json:"errorId"`
+		Name    string `json:"name"`
+		Message string `json:"message"`
 	}
-	if err := json.Unmarshal(b, &full); err != nil {
-		return nil, err
+	_ = json.Unmarshal(b, &nerr)
+	detail := upstream.Excerpt(b)
+	if nerr.ErrorID != "" {
+		detail = strings.TrimSpace(nerr.Name + ": " + nerr.Message)
+	}
+	if e := upstream.FromStatus(c.source, endpoint, resp, detail); e != nil {
+		return e
 	}
+	if nerr.ErrorID == "" {
+		return nil
+	}
+	e := &upstream.Error{Kind: upstream.KindStatus, Source: c.source, Endpoint: endpoint, Status: resp.StatusCode, Detail: detail}
+	switch nerr.Name {
+	case "UnauthorizedError":
+		e.Kind = upstream.KindAuth
+	case "RateLimitedError":
+		e.Kind = upstream.KindRateLimited
+	}
+	return e
+}
 
+func (c *Client) decodeCards(blocks map[string]json.RawMessage, users map[string]string) []Card {
 	type rawBlock struct {
 		Value struct {
 			ParentTable    string                     `json:"parent_table"`
@@ -138,46 +305,10 @@ func (c *Client) Fetch(ctx context.Context) ([]Card, error) {
 		} `json:"value"`
 	}
 
-	parseProps := func(rb rawBlock) (title string, props map[string]string, created int64, updated int64, releasedAt string) {
-		created = rb.Value.CreatedTime
-		updated = rb.Value.LastEditedTime
-		props = make(map[string]string)
-
-		for k, rawv := range rb.Value.Properties {
-			var arr [][]any
-			if err := json.Unmarshal(rawv, &arr); err != nil || len(arr) == 0 {
-				continue
-			}
-			cell := arr[0]
-
-			if len(cell) > 1 {
-				if dateCells, ok := cell[1].([]any); ok && len(dateCells) > 0 {
-					if pair, ok2 := dateCells[0].([]any); ok2 && len(pair) > 1 {
-						if dm, ok3 := pair[1].(map[string]any); ok3 {
-							if ds, ok4 := dm["start_date"].(string); ok4 {
-								props[k] = ds
-								if k == "?igY" {
-									releasedAt = ds
-								}
-								continue
-							}
-						}
-					}
-				}
-			}
-
-			if text, ok := cell[0].(string); ok {
-				props[k] = text
-				if k == "title" {
-					title = text
-				}
-			}
-		}
-		return
-	}
-
+	releasedKey := c.keys()["releasedAt"]
+	live := c.liveProperties()
 	cards := make([]Card, 0, 256)
-	for id, raw := range full.RecordMap.Block {
+	for id, raw := range blocks {
 		var rb rawBlock
 		if err := json.Unmarshal(raw, &rb); err != nil {
 			continue
@@ -185,59 +316,68 @@ func (c *Client) Fetch(ctx context.Context) ([]Card, error) {
 		if rb.Value.ParentTable != "collection" {
 			continue
 		}
-		title, props, createdAt, updatedAt, releasedAt := parseProps(rb)
-		cleanViewID := strings.ReplaceAll(collectionViewID, "-", "")
+		props := make(map[string]PropertyValue, len(rb.Value.Properties))
+		for k, v := range rb.Value.Properties {
+			typ := live[k].Type
+			if k == "title" {
+				typ = "title"
+			}
+			props[k] = decodeProperty(v, typ, users)
+		}
+		cleanViewID := strings.ReplaceAll(c.board.ViewID, "-", "")
 		cleanPageID := strings.ReplaceAll(id, "-", "")
-		url := fmt.Sprintf("%s?v=%s&p=%s&pm=s", notionSiteBaseURL, cleanViewID, cleanPageID)
-		cards = append(cards, Card{
+		url := fmt.Sprintf("%s?v=%s&p=%s&pm=s", c.siteURL, cleanViewID, cleanPageID)
+		card := Card{
 			ID:         id,
-			Title:      title,
+			Title:      props["title"].Text,
 			URL:        url,
 			Properties: props,
-			CreatedAt:  createdAt,
-			UpdatedAt:  updatedAt,
-			ReleasedAt: releasedAt,
-		})
-	}
-
-	if c.cacheTTL > 0 {
-		c.mu.Lock()
-		c.cache = &cacheEntry{data: cards, expiresAt: time.Now().Add(c.cacheTTL)}
-		c.mu.Unlock()
+			CreatedAt:  rb.Value.CreatedTime,
+			UpdatedAt:  rb.Value.LastEditedTime,
+		}
+		if d := props[releasedKey].Date; d != nil {
+			card.ReleasedAt = d.Start
+		}
+		cards = append(cards, card)
 	}
-	return cards, nil
+	return cards
 }
 
 func (c *Client) Probe(ctx context.Context) (int, int, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notionAPIURL, bytes.NewReader(notionQueryPayload))
+	payload, err := c.board.queryPayload(c.keys(), initialGroupLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.queryURL(), bytes.NewReader(payload))
 	if err != nil {
 		return 0, 0, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Cookie", os.Getenv("NOTION_COOKIE"))
-	req.Header.Set("x-notion-space-id", "2a7d9973-2a91-430b-9d0f-520163f17777")
-	req.Header.Set("x-notion-active-user-header", "")
-	req.Header.Set("notion-client-version", "23.13.0.4147")
-	req.Header.Set("notion-audit-log-platform", "web")
+	tracing.Inject(ctx, req.Header)
+	c.session.apply(req, c.board.SpaceID)
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, 0, err
+		c.observe(ctx, "queryCollection", req.URL.String(), 0, time.Since(start), err)
+		return 0, 0, upstream.FromTransport(c.source, "queryCollection", err)
 	}
 	defer resp.Body.Close()
+	c.observe(ctx, "queryCollection", req.URL.String(), resp.StatusCode, time.Since(start), nil)
 
 	status := resp.StatusCode
 	b, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
 	if err != nil {
-		return status, 0, err
+		return status, 0, upstream.FromTransport(c.source, "queryCollection", err)
 	}
-
-	var full struct {
-		RecordMap struct {
-			Block map[string]json.RawMessage `json:"block"`
-		} `json:"recordMap"`
+	if e := c.responseError("queryCollection", resp, b); e != nil {
+		c.session.record(e)
+		return status, 0, e
+	}
+	res, err := c.decodeQuery(b)
+	if err != nil {
+		return status, 0, err
 	}
-	if err := json.Unmarshal(b, &full); err != nil {
+	if err := c.checkQuery(res); err != nil {
 		return status, 0, err
 	}
 
@@ -247,7 +387,7 @@ func (c *Client) Probe(ctx context.Context) (int, int, error) {
 		} `json:"value"`
 	}
 	count := 0
-	for _, raw := range full.RecordMap.Block {
+	for _, raw := range res.blocks {
 		var rb rawBlock
 		if err := json.Unmarshal(raw, &rb); err != nil {
 			continue
@@ -256,31 +396,5 @@ func (c *Client) Probe(ctx context.Context) (int, int, error) {
 			count++
 		}
 	}
-	if status >= 400 {
-		return status, count, fmt.Errorf("notion status %d", status)
-	}
 	return status, count, nil
 }
-
-var keyMap = map[string]string{
-	"3E6J":             "status",
-	"@@W>":             "network",
-	"K:rY":             "category",
-	"\\wxR":            "projectLead",
-	"^NHI":             "releasePost",
-	"created_time":     "createdAt",
-	"last_edited_time": "lastUpdated",
-	"?igY":             "releasedAt",
-}
-
-func renameMap(in map[string]string) map[string]string {
-	out := make(map[string]string, len(in))
-	for k, v := range in {
-		if nk, ok := keyMap[k]; ok {
-			out[nk] = v
-		} else {
-			out[k] = v
-		}
-	}
-	return out
-}
@@ -0,0 +1,155 @@
+package cubecraft
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"roadmapapi/internal/tracing"
+	"roadmapapi/internal/upstream"
+)
+
+const (
+	pageChunkLimit = 100
+	maxPageChunks  = 20
+)
+
+// Content is a card's page rendered to sanitized HTML and plain text.
+type Content struct {
+	HTML string
+	Text string
+}
+
+type contentEntry struct {
+	edited int64
+	Content
+}
+
+// WithPageContent loads each card's page content, at most concurrency pages
+// at a time. Zero disables it.
+func WithPageContent(concurrency int) ClientOption {
+	return func(c *Client) { c.contentConcurrency = concurrency }
+}
+
+// Contents returns the page content of cards, keyed by card ID. Pages are
+// cached until their last edited time changes; pages that fail to load are
+// logged and left out.
+func (c *Client) Contents(ctx context.Context, cards []Card) map[string]Content {
+	out := make(map[string]Content, len(cards))
+	if c.contentConcurrency <= 0 || len(cards) == 0 {
+		return out
+	}
+	ctx, span := tracing.Start(ctx, "cubecraft.contents", tracing.Int("cubecraft.cards", len(cards)))
+	defer span.End()
+
+	var todo []Card
+	c.contentMu.Lock()
+	for _, card := range cards {
+		if e, ok := c.contents[card.ID]; ok && e.edited == card.UpdatedAt {
+			out[card.ID] = e.Content
+		} else {
+			todo = append(todo, card)
+		}
+	}
+	c.contentMu.Unlock()
+	span.SetAttrs(tracing.Int("cubecraft.pages_fetched", len(todo)))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, c.contentConcurrency)
+	for _, card := range todo {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			content, err := c.pageContent(ctx, card.ID)
+			if err != nil {
+				c.logger.WarnContext(ctx, "loading notion page failed", "source", c.source, "card", card.ID, "error", err)
+				return
+			}
+			c.contentMu.Lock()
+			c.contents[card.ID] = contentEntry{edited: card.UpdatedAt, Content: content}
+			c.contentMu.Unlock()
+			mu.Lock()
+			out[card.ID] = content
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// pruneContents drops the cached pages of cards no longer listed on the
+// board, such as deleted cards.
+func (c *Client) pruneContents(res queryResult) {
+	listed := make(map[string]bool)
+	for _, g := range res.groups {
+		for _, id := range g.blockIDs {
+			listed[id] = true
+		}
+	}
+	c.contentMu.Lock()
+	defer c.contentMu.Unlock()
+	for id := range c.contents {
+		if !listed[id] {
+			delete(c.contents, id)
+		}
+	}
+}
+
+// pageContent loads every chunk of a page's blocks with loadCachedPageChunk
+// and renders them.
+func (c *Client) pageContent(ctx context.Context, pageID string) (Content, error) {
+	blocks := make(map[string]notionBlock)
+	cursor := json.RawMessage(`{"stack":[]}`)
+	for chunk := 0; chunk < maxPageChunks; chunk++ {
+		body, err := json.Marshal(map[string]any{
+			"page":            map[string]string{"id": pageID, "spaceId": c.board.SpaceID},
+			"limit":           pageChunkLimit,
+			"cursor":          cursor,
+			"chunkNumber":     chunk,
+			"verticalColumns": false,
+		})
+		if err != nil {
+			return Content{}, err
+		}
+		b, err := c.post(ctx, "loadCachedPageChunk", c.apiURL+"/loadCachedPageChunk", body)
+		if err != nil {
+			return Content{}, err
+		}
+		var resp struct {
+			Cursor struct {
+				Stack json.RawMessage `json:"stack"`
+			} `json:"cursor"`
+			RecordMap struct {
+				Block map[string]struct {
+					Value notionBlock `json:"value"`
+				} `json:"block"`
+			} `json:"recordMap"`
+		}
+		if err := json.Unmarshal(b, &resp); err != nil {
+			return Content{}, &upstream.Error{Kind: upstream.KindDecode, Source: c.source, Endpoint: "loadCachedPageChunk", Err: err}
+		}
+		if _, ok := resp.RecordMap.Block[pageID]; chunk == 0 && !ok {
+			// Caching this would hide the page until it is next edited.
+			return Content{}, &upstream.Error{Kind: upstream.KindEmpty, Source: c.source, Endpoint: "loadCachedPageChunk", Detail: "page " + pageID + " not returned"}
+		}
+		for id, rb := range resp.RecordMap.Block {
+			if rb.Value.ID == "" {
+				rb.Value.ID = id
+			}
+			blocks[id] = rb.Value
+		}
+		var stack []json.RawMessage
+		if err := json.Unmarshal(resp.Cursor.Stack, &stack); err != nil || len(stack) == 0 {
+			break
+		}
+		cursor, _ = json.Marshal(resp.Cursor)
+	}
+	return renderPage(pageID, blocks), nil
+}
@@ -0,0 +1,21 @@
+package cubecraft
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestPruneContents(t *testing.T) {
+	c := NewClient()
+	for _, id := range []string{"kept", "hidden", "deleted"} {
+		c.contents[id] = contentEntry{edited: 1, Content: Content{Text: id}}
+	}
+	c.pruneContents(queryResult{groups: []boardGroup{
+		{name: "In Progress", blockIDs: []string{"kept", "new"}},
+		{name: "Scrapped", blockIDs: []string{"hidden"}},
+	}})
+	if got := slices.Sorted(maps.Keys(c.contents)); !slices.Equal(got, []string{"hidden", "kept"}) {
+		t.Errorf("cached pages after prune = %v, want [hidden kept]", got)
+	}
+}
@@ -1,66 +1,110 @@
 package cubecraft
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"roadmapapi/internal/hive"
+
+	"roadmapapi/internal/problem"
+	"roadmapapi/internal/roadmap"
 )
 
+type HandlerOption func(*Handlers)
+
+// WithArchive enables ?at= on column endpoints.
+func WithArchive(a roadmap.Archive) HandlerOption {
+	return func(h *Handlers) { h.archive = a }
+}
+
 type Handlers struct {
-	svc Service
+	svc     Service
+	archive roadmap.Archive
 }
 
-func NewHandlers(s Service) *Handlers { return &Handlers{svc: s} }
+func NewHandlers(s Service, opts ...HandlerOption) *Handlers {
+	h := &Handlers{svc: s}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
 
 func (h *Handlers) Columns(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]any{"columns": h.svc.Columns()})
+	writeJSON(w, http.StatusOK, map[string]any{"columns": h.svc.Columns(), "native": h.svc.NativeColumns()})
+}
+
+// Schema shows the live collection schema and how the board's fields map
+// onto it.
+func (h *Handlers) Schema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.svc.Schema(r.Context()))
 }
 
 func (h *Handlers) ByColumn(w http.ResponseWriter, r *http.Request) {
 	column := strings.ToLower(chi.URLParam(r, "column"))
-	if _, ok := columnToStatus[column]; !ok {
-		httpError(w, http.StatusBadRequest, "column must be one of [in-progress, coming-next, released]")
+	board := h.svc.Board()
+	values, ok := board.column(column)
+	if !ok {
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidColumn, board.columnError().Error()).
+			With("columns", board.ColumnNames()).With("native", slices.Sorted(maps.Keys(board.NativeColumns()))))
+		return
+	}
+	if r.URL.Query().Has("at") {
+		if col, ok := h.archived(w, r, h.svc.Name(), column); ok {
+			writeJSON(w, http.StatusOK, flattenPages(archivedPages(col), board, h.svc.Name()))
+		}
 		return
 	}
 	sortBy := strFromQuery(r, "sortBy", "")
 	pages, err := h.svc.All(r.Context(), column, defaultPageSize, sortBy)
 	if err != nil {
-		httpError(w, http.StatusBadGateway, err.Error())
+		problem.UpstreamError(w, r, h.svc.Name(), err)
 		return
 	}
-	all := flattenPages(pages)
+	all := flattenPages(pages, board, h.svc.Name())
+	if t := h.svc.Truncation().Only(values); t != nil {
+		w.Header().Set("X-Upstream-Truncated", strconv.Itoa(t.Missing))
+		all.Truncated = t
+	}
 	writeJSON(w, http.StatusOK, all)
 }
 
 type cubeItemOut struct {
-	ID               string `json:"id"`
-	Slug             string `json:"slug"`
-	Title            string `json:"title"`
-	Status           string `json:"status"`
-	Category         string `json:"category"`
-	Network          string `json:"network,omitempty"`
-	ProjectLead      string `json:"projectLead,omitempty"`
-	Date             string `json:"date"`
-	LastModified     string `json:"lastModified"`
-	ETA              string `json:"eta,omitempty"`
-	Released         bool   `json:"released"`
-	ReleasedAt       string `json:"releasedAt,omitempty"`
-	DateUnix         int64  `json:"dateUnix"`
-	LastModifiedUnix int64  `json:"lastModifiedUnix"`
-	URL              string `json:"url,omitempty"`
-	Source           string `json:"source"`
+	ID               string                   `json:"id"`
+	Slug             string                   `json:"slug"`
+	Title            string                   `json:"title"`
+	Status           string                   `json:"status"`
+	Category         string                   `json:"category"`
+	Network          string                   `json:"network,omitempty"`
+	ProjectLead      string                   `json:"projectLead,omitempty"`
+	Date             string                   `json:"date"`
+	LastModified     string                   `json:"lastModified"`
+	ETA              string                   `json:"eta,omitempty"`
+	ContentHTML      string                   `json:"contentHtml,omitempty"`
+	ContentText      string                   `json:"contentText,omitempty"`
+	Released         bool                     `json:"released"`
+	ReleasedAt       string                   `json:"releasedAt,omitempty"`
+	DateUnix         int64                    `json:"dateUnix"`
+	LastModifiedUnix int64                    `json:"lastModifiedUnix"`
+	URL              string                   `json:"url,omitempty"`
+	Properties       map[string]PropertyValue `json:"properties,omitempty"`
+	Source           string                   `json:"source"`
 }
 
 func (h *Handlers) Updates(w http.ResponseWriter, _ *http.Request) {
 	entries := h.svc.Updates()
+	board := h.svc.Board()
 	type changeOut struct {
 		ChangedAt   string      `json:"changedAt"`
 		ChangedAtMS int64       `json:"changedAtMs"`
+		Type        string      `json:"type"`
 		From        string      `json:"from"`
 		To          string      `json:"to"`
 		Item        cubeItemOut `json:"item"`
@@ -72,10 +116,11 @@ func (h *Handlers) Updates(w http.ResponseWriter, _ *http.Request) {
 		etaStr := isoOrEmpty(e.Item.ReleasedAt)
 		dateUnix := e.Item.CreatedAt.Unix()
 		lmUnix := e.Item.UpdatedAt.Unix()
-		released := strings.EqualFold(e.Item.Status, "Released")
+		released := board.released(e.Item.Status)
 		out = append(out, changeOut{
 			ChangedAt:   e.At.Format(time.RFC3339),
 			ChangedAtMS: e.At.UnixMilli(),
+			Type:        cmp.Or(e.Type, "status_change"),
 			From:        e.From,
 			To:          e.To,
 			Item: cubeItemOut{
@@ -94,16 +139,59 @@ func (h *Handlers) Updates(w http.ResponseWriter, _ *http.Request) {
 				DateUnix:         dateUnix,
 				LastModifiedUnix: lmUnix,
 				URL:              e.Item.URL,
-				Source:           "cubecraft",
+				Properties:       e.Item.Properties,
+				Source:           h.svc.Name(),
 			},
 		})
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"updates": out})
 }
 
-func flattenPages(pages []hive.RoadmapPage) struct {
+type itemsOut struct {
 	Items []cubeItemOut `json:"items"`
-} {
+	// Truncated is set when the board could not be loaded completely.
+	Truncated *Truncation `json:"truncated,omitempty"`
+}
+
+// archived serves column as of ?at= from the archive, setting the
+// X-Snapshot-* headers, or writes the error and returns false.
+func (h *Handlers) archived(w http.ResponseWriter, r *http.Request, source, column string) (roadmap.ArchivedColumn, bool) {
+	col, err := roadmap.LookupArchived(h.archive, source, column, r.URL.Query().Get("at"))
+	switch {
+	case errors.Is(err, roadmap.ErrArchiveDisabled):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeFeatureDisabled, err)
+		return col, false
+	case errors.Is(err, roadmap.ErrInvalidAt):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err)
+		return col, false
+	case errors.Is(err, roadmap.ErrNotArchived):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, err)
+		return col, false
+	case err != nil:
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, err)
+		return col, false
+	}
+	w.Header().Set("X-Snapshot-Id", col.SnapshotID)
+	w.Header().Set("X-Snapshot-Taken-At", col.TakenAt.UTC().Format(time.RFC3339))
+	return col, true
+}
+
+// archivedPages wraps a column read from the archive, which keeps no Notion
+// properties.
+func archivedPages(col roadmap.ArchivedColumn) []Page {
+	pages := col.Pages()
+	out := make([]Page, len(pages))
+	for i, p := range pages {
+		items := make([]PageItem, len(p.Items))
+		for j, it := range p.Items {
+			items[j] = PageItem{Item: it}
+		}
+		out[i] = Page{Meta: p.Meta, Items: items}
+	}
+	return out
+}
+
+func flattenPages(pages []Page, board Board, source string) itemsOut {
 	out := make([]cubeItemOut, 0, 512)
 	for _, p := range pages {
 		for _, it := range p.Items {
@@ -114,7 +202,7 @@ func flattenPages(pages []hive.RoadmapPage) struct {
 			if t, err := time.Parse(time.RFC3339, it.LastModified); err == nil {
 				lmUnix = t.Unix()
 			}
-			released := strings.EqualFold(it.Status, "Released")
+			released := board.released(it.Status)
 			releasedAt := it.ETA
 			out = append(out, cubeItemOut{
 				ID:               it.ID,
@@ -127,18 +215,19 @@ func flattenPages(pages []hive.RoadmapPage) struct {
 				Date:             it.Date,
 				LastModified:     it.LastModified,
 				ETA:              it.ETA,
+				ContentHTML:      it.ContentHTML,
+				ContentText:      it.ContentText,
 				Released:         released,
 				ReleasedAt:       releasedAt,
 				DateUnix:         dateUnix,
 				LastModifiedUnix: lmUnix,
 				URL:              it.URL,
-				Source:           "cubecraft",
+				Properties:       it.Properties,
+				Source:           source,
 			})
 		}
 	}
-	return struct {
-		Items []cubeItemOut `json:"items"`
-	}{Items: out}
+	return itemsOut{Items: out}
 }
 
 func intFromQuery(r *http.Request, key string, def int) int {
@@ -178,7 +267,3 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.WriteHeader(code)
 	_ = json.NewEncoder(w).Encode(v)
 }
-
-func httpError(w http.ResponseWriter, code int, msg string) {
-	writeJSON(w, code, map[string]any{"error": msg})
-}
@@ -1,36 +1,68 @@
 package cubecraft
 
-import "time"
+import (
+	"time"
+
+	"roadmapapi/internal/roadmap"
+)
 
 type Card struct {
-	ID         string            `json:"id"`
-	Title      string            `json:"title"`
-	URL        string            `json:"url"`
-	Properties map[string]string `json:"properties"`
-	CreatedAt  int64             `json:"createdAt"`
-	UpdatedAt  int64             `json:"lastUpdated"`
-	ReleasedAt string            `json:"releasedAt"`
+	ID         string                   `json:"id"`
+	Title      string                   `json:"title"`
+	URL        string                   `json:"url"`
+	Properties map[string]PropertyValue `json:"properties"`
+	CreatedAt  int64                    `json:"createdAt"`
+	UpdatedAt  int64                    `json:"lastUpdated"`
+	ReleasedAt string                   `json:"releasedAt"`
 }
 
 type item struct {
-	ID          string
-	Slug        string
-	Title       string
-	Status      string
-	Category    string
-	Network     string
-	ProjectLead string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	ReleasedAt  time.Time
-	URL         string
-	ContentHTML string
-	ContentText string
+	ID          string                   `json:"id"`
+	Slug        string                   `json:"slug"`
+	Title       string                   `json:"title"`
+	Status      string                   `json:"status"`
+	Category    string                   `json:"category"`
+	Network     string                   `json:"network"`
+	ProjectLead string                   `json:"projectLead"`
+	CreatedAt   time.Time                `json:"createdAt"`
+	UpdatedAt   time.Time                `json:"updatedAt"`
+	ReleasedAt  time.Time                `json:"releasedAt,omitzero"`
+	URL         string                   `json:"url"`
+	Properties  map[string]PropertyValue `json:"properties,omitempty"`
+	ContentHTML string                   `json:"contentHtml,omitempty"`
+	ContentText string                   `json:"contentText,omitempty"`
+	// value is the card's GroupBy value, before Labels apply.
+	value string
 }
 
 type statusChange struct {
-	At   time.Time
-	From string
-	To   string
-	Item item
+	At time.Time `json:"at"`
+	// Type is the board's event name for the move, or "status_change".
+	Type string `json:"type"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	Item item   `json:"item"`
+}
+
+// Page is a page of board cards as roadmap items.
+type Page struct {
+	Meta  roadmap.PageMeta `json:"meta"`
+	Items []PageItem       `json:"items"`
+}
+
+// PageItem is a card as a roadmap item, with its Notion properties by field
+// or property name.
+type PageItem struct {
+	roadmap.Item
+	Properties map[string]PropertyValue `json:"properties,omitempty"`
+}
+
+// Roadmap drops the Notion properties, which the archive and the history
+// do not keep.
+func (p Page) Roadmap() roadmap.Page {
+	items := make([]roadmap.Item, len(p.Items))
+	for i, it := range p.Items {
+		items[i] = it.Item
+	}
+	return roadmap.Page{Meta: p.Meta, Items: items}
 }
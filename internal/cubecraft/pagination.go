@@ -0,0 +1,315 @@
+package cubecraft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/tracing"
+	"roadmapapi/internal/upstream"
+)
+
+const (
+	// initialGroupLimit is how many cards per board group the first query
+	// asks for. Groups holding more are re-queried with a higher limit.
+	initialGroupLimit = 50
+	maxGroupLimit     = 1000
+	maxQueryAttempts  = 3
+	syncBatchSize     = 100
+)
+
+var missingCards = metrics.Default.NewGaugeVec(
+	"roadmap_cubecraft_missing_cards",
+	"Cards per board group the last Notion fetch could not load.",
+	"source", "group",
+)
+
+// GroupCount is a board group's card count as reported by Notion and how
+// many of its cards were actually loaded.
+type GroupCount struct {
+	Group    string `json:"group"`
+	Expected int    `json:"expected"`
+	Loaded   int    `json:"loaded"`
+}
+
+// Truncation describes cards the last fetch could not load.
+type Truncation struct {
+	Missing int          `json:"missing"`
+	Groups  []GroupCount `json:"groups"`
+}
+
+// Truncation returns what the most recent fetch failed to load, or nil if
+// it loaded the whole board.
+func (c *Client) Truncation() *Truncation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.truncation
+}
+
+// Only narrows t to the given groups; it returns nil if none of them are
+// truncated.
+func (t *Truncation) Only(groups []string) *Truncation {
+	if t == nil {
+		return nil
+	}
+	var out Truncation
+	for _, g := range t.Groups {
+		if slices.Contains(groups, g.Group) {
+			out.Groups = append(out.Groups, g)
+			out.Missing += g.Expected - g.Loaded
+		}
+	}
+	if out.Missing == 0 {
+		return nil
+	}
+	return &out
+}
+
+type boardGroup struct {
+	name     string
+	expected int
+	blockIDs []string
+	hasMore  bool
+}
+
+type queryResult struct {
+	groups []boardGroup
+	blocks map[string]json.RawMessage
+	// users maps the IDs of users in the record map to their names.
+	users map[string]string
+	// emptyRecordMap is set when the response carried no records at all.
+	emptyRecordMap bool
+}
+
+// checkQuery rejects query results that cannot be a board's content and
+// records whether they show Notion accepting the session. A board Notion
+// lists cards for, or serves no records of, is one the cookie has no access
+// to; one without any cards is a failed load, not an empty roadmap.
+func (c *Client) checkQuery(res queryResult) error {
+	listed := 0
+	for _, g := range res.groups {
+		listed += len(g.blockIDs)
+	}
+	if res.emptyRecordMap || (len(res.blocks) == 0 && listed > 0) {
+		err := &upstream.Error{Kind: upstream.KindAuth, Source: c.source, Endpoint: "queryCollection", Status: http.StatusOK, Detail: "empty recordMap"}
+		c.session.record(err)
+		return err
+	}
+	c.session.record(nil)
+	if listed == 0 {
+		return &upstream.Error{Kind: upstream.KindEmpty, Source: c.source, Endpoint: "queryCollection", Status: http.StatusOK, Detail: fmt.Sprintf("no cards in %d groups", len(res.groups))}
+	}
+	return nil
+}
+
+// load queries the board until every group's cards are listed, fetches any
+// listed card missing from the record map with syncRecordValues, and
+// reports whatever still could not be loaded.
+func (c *Client) load(ctx context.Context) (queryResult, *Truncation, error) {
+	ctx, span := tracing.Start(ctx, "cubecraft.load")
+	defer span.End()
+
+	keys := c.resolveSchema(ctx)
+	if err := c.schemaError(); err != nil {
+		span.RecordError(err)
+		return queryResult{}, nil, err
+	}
+	var res queryResult
+	limit := initialGroupLimit
+	for attempt := 1; ; attempt++ {
+		payload, err := c.board.queryPayload(keys, limit)
+		if err != nil {
+			span.RecordError(err)
+			return queryResult{}, nil, err
+		}
+		b, err := c.post(ctx, "queryCollection", c.queryURL(), payload)
+		if err != nil {
+			span.RecordError(err)
+			return queryResult{}, nil, err
+		}
+		if res, err = c.decodeQuery(b); err != nil {
+			span.RecordError(err)
+			return queryResult{}, nil, err
+		}
+		if err := c.checkQuery(res); err != nil {
+			span.RecordError(err)
+			return queryResult{}, nil, err
+		}
+		need := 0
+		for _, g := range res.groups {
+			if g.hasMore || g.expected > len(g.blockIDs) {
+				need = max(need, g.expected, len(g.blockIDs)+1)
+			}
+		}
+		if need == 0 || limit >= maxGroupLimit || attempt == maxQueryAttempts {
+			break
+		}
+		limit = min(max(need, 2*limit), maxGroupLimit)
+	}
+	span.SetAttrs(tracing.Int("cubecraft.group_limit", limit))
+
+	var missing []string
+	for _, g := range res.groups {
+		for _, id := range g.blockIDs {
+			if _, ok := res.blocks[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+	}
+	for i := 0; i < len(missing); i += syncBatchSize {
+		batch := missing[i:min(i+syncBatchSize, len(missing))]
+		blocks, err := c.syncBlocks(ctx, batch)
+		if err != nil {
+			// What was loaded is still useful; the gap is reported below.
+			c.logger.WarnContext(ctx, "loading notion cards failed", "source", c.source, "error", err, "cards", len(batch))
+			break
+		}
+		for id, raw := range blocks {
+			res.blocks[id] = raw
+		}
+	}
+	span.SetAttrs(tracing.Int("cubecraft.synced_cards", len(missing)))
+
+	trunc := truncation(res)
+	for _, g := range res.groups {
+		missingCards.With(c.source, g.name).Set(0)
+	}
+	if trunc != nil {
+		for _, g := range trunc.Groups {
+			missingCards.With(c.source, g.Group).Set(float64(g.Expected - g.Loaded))
+		}
+		c.logger.WarnContext(ctx, "notion board truncated", "source", c.source, "missing", trunc.Missing, "groups", trunc.Groups)
+	}
+	return res, trunc, nil
+}
+
+func truncation(res queryResult) *Truncation {
+	var t Truncation
+	for _, g := range res.groups {
+		loaded := 0
+		for _, id := range g.blockIDs {
+			if _, ok := res.blocks[id]; ok {
+				loaded++
+			}
+		}
+		if loaded < g.expected {
+			t.Groups = append(t.Groups, GroupCount{Group: g.name, Expected: g.expected, Loaded: loaded})
+			t.Missing += g.expected - loaded
+		}
+	}
+	if t.Missing == 0 {
+		return nil
+	}
+	sort.Slice(t.Groups, func(i, j int) bool { return t.Groups[i].Group < t.Groups[j].Group })
+	return &t
+}
+
+func (c *Client) decodeQuery(b []byte) (queryResult, error) {
+	var full struct {
+		Result struct {
+			ReducerResults struct {
+				BoardColumns struct {
+					Results []struct {
+						Value struct {
+							Value any `json:"value"`
+						} `json:"value"`
+						BlockIDs          []string `json:"blockIds"`
+						HasMore           bool     `json:"hasMore"`
+						Total             int      `json:"total"`
+						AggregationResult struct {
+							Value float64 `json:"value"`
+						} `json:"aggregationResult"`
+					} `json:"results"`
+				} `json:"board_columns"`
+			} `json:"reducerResults"`
+		} `json:"result"`
+		RecordMap struct {
+			Block          map[string]json.RawMessage `json:"block"`
+			Collection     map[string]json.RawMessage `json:"collection"`
+			CollectionView map[string]json.RawMessage `json:"collection_view"`
+			NotionUser     map[string]struct {
+				Value struct {
+					Name       string `json:"name"`
+					GivenName  string `json:"given_name"`
+					FamilyName string `json:"family_name"`
+				} `json:"value"`
+			} `json:"notion_user"`
+		} `json:"recordMap"`
+	}
+	if err := json.Unmarshal(b, &full); err != nil {
+		return queryResult{}, &upstream.Error{Kind: upstream.KindDecode, Source: c.source, Endpoint: "queryCollection", Err: err}
+	}
+	rm := full.RecordMap
+	res := queryResult{
+		blocks:         rm.Block,
+		users:          make(map[string]string),
+		emptyRecordMap: len(rm.Block) == 0 && len(rm.Collection) == 0 && len(rm.CollectionView) == 0,
+	}
+	for id, u := range full.RecordMap.NotionUser {
+		name := u.Value.Name
+		if name == "" {
+			name = strings.TrimSpace(u.Value.GivenName + " " + u.Value.FamilyName)
+		}
+		if name != "" {
+			res.users[id] = name
+		}
+	}
+	if res.blocks == nil {
+		res.blocks = make(map[string]json.RawMessage)
+	}
+	for _, r := range full.Result.ReducerResults.BoardColumns.Results {
+		name := "(none)"
+		if r.Value.Value != nil {
+			name = fmt.Sprint(r.Value.Value)
+		}
+		res.groups = append(res.groups, boardGroup{
+			name:     name,
+			expected: max(r.Total, int(r.AggregationResult.Value), len(r.BlockIDs)),
+			blockIDs: r.BlockIDs,
+			hasMore:  r.HasMore,
+		})
+	}
+	return res, nil
+}
+
+// syncBlocks loads blocks by ID through syncRecordValues.
+func (c *Client) syncBlocks(ctx context.Context, ids []string) (map[string]json.RawMessage, error) {
+	return c.syncRecords(ctx, "block", ids)
+}
+
+// syncRecords loads records of one table by ID through syncRecordValues.
+func (c *Client) syncRecords(ctx context.Context, table string, ids []string) (map[string]json.RawMessage, error) {
+	type pointer struct {
+		Table   string `json:"table"`
+		ID      string `json:"id"`
+		SpaceID string `json:"spaceId"`
+	}
+	type request struct {
+		Pointer pointer `json:"pointer"`
+		Version int     `json:"version"`
+	}
+	reqs := make([]request, len(ids))
+	for i, id := range ids {
+		reqs[i] = request{Pointer: pointer{Table: table, ID: id, SpaceID: c.board.SpaceID}, Version: -1}
+	}
+	body, err := json.Marshal(map[string]any{"requests": reqs})
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.post(ctx, "syncRecordValues", c.syncURL(), body)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		RecordMap map[string]map[string]json.RawMessage `json:"recordMap"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, &upstream.Error{Kind: upstream.KindDecode, Source: c.source, Endpoint: "syncRecordValues", Err: err}
+	}
+	return out.RecordMap[table], nil
+}
@@ -0,0 +1,268 @@
+package cubecraft
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyValue is a decoded Notion property. Type is the schema type, or
+// one inferred from the value when the schema is unknown; which other
+// fields are set depends on it.
+type PropertyValue struct {
+	Type string `json:"type"`
+	// Text is the plain text of the value, with mentions resolved.
+	Text     string     `json:"text,omitempty"`
+	Options  []string   `json:"options,omitempty"`
+	Number   *float64   `json:"number,omitempty"`
+	Checkbox *bool      `json:"checkbox,omitempty"`
+	URL      string     `json:"url,omitempty"`
+	People   []Person   `json:"people,omitempty"`
+	Pages    []string   `json:"pages,omitempty"`
+	Date     *DateValue `json:"date,omitempty"`
+}
+
+// Person is a mentioned Notion user. Name is only known when the response
+// included the user record.
+type Person struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// DateValue is a date or date range. Start and End are dates (2006-01-02)
+// or, when HasTime is set, RFC 3339 timestamps in TimeZone.
+type DateValue struct {
+	Start    string `json:"start"`
+	End      string `json:"end,omitempty"`
+	HasTime  bool   `json:"hasTime,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// StartTime returns the start as a time; date-only values are midnight UTC.
+func (d *DateValue) StartTime() time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	layout := "2006-01-02"
+	if d.HasTime {
+		layout = time.RFC3339
+	}
+	t, _ := time.Parse(layout, d.Start)
+	return t
+}
+
+// String flattens the value to text, as the roadmap fields expose it.
+func (v PropertyValue) String() string {
+	switch v.Type {
+	case "date":
+		if v.Date == nil {
+			return ""
+		}
+		if v.Date.End != "" {
+			return v.Date.Start + "/" + v.Date.End
+		}
+		return v.Date.Start
+	case "multi_select":
+		return strings.Join(v.Options, ", ")
+	case "number":
+		if v.Number == nil {
+			return v.Text
+		}
+		return strconv.FormatFloat(*v.Number, 'f', -1, 64)
+	case "person":
+		names := make([]string, len(v.People))
+		for i, p := range v.People {
+			names[i] = p.Name
+			if p.Name == "" {
+				names[i] = p.ID
+			}
+		}
+		return strings.Join(names, ", ")
+	case "relation":
+		return strings.Join(v.Pages, ", ")
+	}
+	return v.Text
+}
+
+// rawSegment is one [text, [[mark, args...], ...]] cell of a property.
+type rawSegment struct {
+	text  string
+	marks [][]json.RawMessage
+}
+
+func rawSegments(raw json.RawMessage) []rawSegment {
+	var cells [][]json.RawMessage
+	if err := json.Unmarshal(raw, &cells); err != nil {
+		return nil
+	}
+	out := make([]rawSegment, 0, len(cells))
+	for _, c := range cells {
+		if len(c) == 0 {
+			continue
+		}
+		var seg rawSegment
+		if err := json.Unmarshal(c[0], &seg.text); err != nil {
+			continue
+		}
+		if len(c) > 1 {
+			_ = json.Unmarshal(c[1], &seg.marks)
+		}
+		out = append(out, seg)
+	}
+	return out
+}
+
+func markName(m []json.RawMessage) string {
+	var s string
+	if len(m) > 0 {
+		_ = json.Unmarshal(m[0], &s)
+	}
+	return s
+}
+
+func markString(m []json.RawMessage, i int) string {
+	var s string
+	if len(m) > i {
+		_ = json.Unmarshal(m[i], &s)
+	}
+	return s
+}
+
+// decodeProperty decodes a property value in Notion's annotated rich text
+// format. users maps user IDs to names for person mentions.
+func decodeProperty(raw json.RawMessage, typ string, users map[string]string) PropertyValue {
+	segs := rawSegments(raw)
+	if typ == "" {
+		typ = inferType(segs)
+	}
+	v := PropertyValue{Type: typ}
+	var text strings.Builder
+	for _, s := range segs {
+		mention := false
+		for _, m := range s.marks {
+			switch markName(m) {
+			case "u":
+				p := Person{ID: markString(m, 1)}
+				p.Name = users[p.ID]
+				v.People = append(v.People, p)
+				if p.Name != "" {
+					text.WriteString("@" + p.Name)
+				}
+				mention = true
+			case "p":
+				v.Pages = append(v.Pages, markString(m, 1))
+				mention = true
+			case "d":
+				if len(m) > 1 {
+					if d := decodeDate(m[1]); d != nil {
+						if v.Date == nil {
+							v.Date = d
+						}
+						text.WriteString(d.Start)
+					}
+				}
+				mention = true
+			case "a":
+				if v.URL == "" {
+					v.URL = markString(m, 1)
+				}
+			}
+		}
+		if !mention {
+			text.WriteString(s.text)
+		}
+	}
+	v.Text = strings.TrimSpace(text.String())
+
+	switch typ {
+	case "person", "relation", "date":
+		// The text is only mention placeholders.
+		v.Text = ""
+	case "multi_select":
+		for _, o := range strings.Split(v.Text, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				v.Options = append(v.Options, o)
+			}
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(strings.ReplaceAll(v.Text, ",", ""), 64); err == nil {
+			v.Number = &n
+		}
+	case "checkbox":
+		b := v.Text == "Yes"
+		v.Checkbox = &b
+	case "url":
+		if v.URL == "" {
+			v.URL = v.Text
+		}
+	case "email":
+		if v.URL == "" && v.Text != "" {
+			v.URL = "mailto:" + v.Text
+		}
+	case "phone_number":
+		if v.URL == "" && v.Text != "" {
+			v.URL = "tel:" + strings.ReplaceAll(v.Text, " ", "")
+		}
+	}
+	return v
+}
+
+// inferType guesses a property's type from its mentions when the schema is
+// not known.
+func inferType(segs []rawSegment) string {
+	for _, s := range segs {
+		for _, m := range s.marks {
+			switch markName(m) {
+			case "d":
+				return "date"
+			case "u":
+				return "person"
+			case "p":
+				return "relation"
+			}
+		}
+	}
+	return "text"
+}
+
+// decodeDate reads a date mention: {type, start_date, start_time, end_date,
+// end_time, time_zone}.
+func decodeDate(raw json.RawMessage) *DateValue {
+	var d struct {
+		Type      string `json:"type"`
+		StartDate string `json:"start_date"`
+		StartTime string `json:"start_time"`
+		EndDate   string `json:"end_date"`
+		EndTime   string `json:"end_time"`
+		TimeZone  string `json:"time_zone"`
+	}
+	if err := json.Unmarshal(raw, &d); err != nil || d.StartDate == "" {
+		return nil
+	}
+	out := &DateValue{TimeZone: d.TimeZone, HasTime: d.StartTime != ""}
+	loc := time.UTC
+	if d.TimeZone != "" {
+		if l, err := time.LoadLocation(d.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	format := func(date, clock string) string {
+		if !out.HasTime {
+			return date
+		}
+		if clock == "" {
+			clock = "00:00"
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, loc)
+		if err != nil {
+			return date
+		}
+		return t.Format(time.RFC3339)
+	}
+	out.Start = format(d.StartDate, d.StartTime)
+	if d.EndDate != "" && (d.Type == "daterange" || d.Type == "datetimerange" || d.EndDate != d.StartDate || d.EndTime != "") {
+		out.End = format(d.EndDate, d.EndTime)
+	}
+	return out
+}
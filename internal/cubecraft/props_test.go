@@ -0,0 +1,155 @@
+package cubecraft
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	_ "time/tzdata"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// The payloads are property values as loadPageChunk and queryCollection
+// return them in a block's "properties".
+func TestDecodeProperty(t *testing.T) {
+	users := map[string]string{"6b5a1b0e-7c3f-4d3c-9d8e-2f1a4b5c6d7e": "Lewis"}
+	tests := []struct {
+		name string
+		raw  string
+		typ  string
+		want PropertyValue
+	}{
+		{
+			name: "multi_select",
+			raw:  `[["Java,Bedrock, Lobby"]]`,
+			typ:  "multi_select",
+			want: PropertyValue{Type: "multi_select", Text: "Java,Bedrock, Lobby", Options: []string{"Java", "Bedrock", "Lobby"}},
+		},
+		{
+			name: "person with known and unknown users",
+			raw:  `[["‣",[["u","6b5a1b0e-7c3f-4d3c-9d8e-2f1a4b5c6d7e"]]],[","],["‣",[["u","0f9e8d7c-6b5a-4321-8765-43210fedcba9"]]]]`,
+			typ:  "person",
+			want: PropertyValue{Type: "person", People: []Person{
+				{ID: "6b5a1b0e-7c3f-4d3c-9d8e-2f1a4b5c6d7e", Name: "Lewis"},
+				{ID: "0f9e8d7c-6b5a-4321-8765-43210fedcba9"},
+			}},
+		},
+		{
+			name: "relation",
+			raw:  `[["‣",[["p","1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f","b7a3c1d2-0000-4000-8000-000000000001"]]],[","],["‣",[["p","2d3e4f5a-6b7c-4d8e-9f0a-1b2c3d4e5f6a"]]]]`,
+			typ:  "relation",
+			want: PropertyValue{Type: "relation", Pages: []string{"1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f", "2d3e4f5a-6b7c-4d8e-9f0a-1b2c3d4e5f6a"}},
+		},
+		{
+			name: "date",
+			raw:  `[["‣",[["d",{"type":"date","start_date":"2024-05-01"}]]]]`,
+			typ:  "date",
+			want: PropertyValue{Type: "date", Date: &DateValue{Start: "2024-05-01"}},
+		},
+		{
+			name: "date range with time zone",
+			raw:  `[["‣",[["d",{"type":"datetimerange","start_date":"2024-05-01","start_time":"09:30","end_date":"2024-05-03","end_time":"17:00","time_zone":"Europe/London"}]]]]`,
+			typ:  "date",
+			want: PropertyValue{Type: "date", Date: &DateValue{
+				Start:    "2024-05-01T09:30:00+01:00",
+				End:      "2024-05-03T17:00:00+01:00",
+				HasTime:  true,
+				TimeZone: "Europe/London",
+			}},
+		},
+		{
+			name: "date with equal end is not a range",
+			raw:  `[["‣",[["d",{"type":"date","start_date":"2024-05-01","end_date":"2024-05-01"}]]]]`,
+			typ:  "date",
+			want: PropertyValue{Type: "date", Date: &DateValue{Start: "2024-05-01"}},
+		},
+		{
+			name: "number with thousands separator",
+			raw:  `[["1,250.5"]]`,
+			typ:  "number",
+			want: PropertyValue{Type: "number", Text: "1,250.5", Number: ptr(1250.5)},
+		},
+		{
+			name: "unparsable number keeps text",
+			raw:  `[["soon"]]`,
+			typ:  "number",
+			want: PropertyValue{Type: "number", Text: "soon"},
+		},
+		{
+			name: "checkbox checked",
+			raw:  `[["Yes"]]`,
+			typ:  "checkbox",
+			want: PropertyValue{Type: "checkbox", Text: "Yes", Checkbox: ptr(true)},
+		},
+		{
+			name: "checkbox unchecked",
+			raw:  `[["No"]]`,
+			typ:  "checkbox",
+			want: PropertyValue{Type: "checkbox", Text: "No", Checkbox: ptr(false)},
+		},
+		{
+			name: "url from text",
+			raw:  `[["https://www.cubecraft.net/threads/roadmap.1234/"]]`,
+			typ:  "url",
+			want: PropertyValue{Type: "url", Text: "https://www.cubecraft.net/threads/roadmap.1234/", URL: "https://www.cubecraft.net/threads/roadmap.1234/"},
+		},
+		{
+			name: "url from link mark",
+			raw:  `[["forum post",[["a","https://www.cubecraft.net/"]]]]`,
+			typ:  "url",
+			want: PropertyValue{Type: "url", Text: "forum post", URL: "https://www.cubecraft.net/"},
+		},
+		{
+			name: "unknown schema type keeps text",
+			raw:  `[["=42",[["b"]]]]`,
+			typ:  "formula",
+			want: PropertyValue{Type: "formula", Text: "=42"},
+		},
+		{
+			name: "no schema infers person",
+			raw:  `[["‣",[["u","6b5a1b0e-7c3f-4d3c-9d8e-2f1a4b5c6d7e"]]]]`,
+			want: PropertyValue{Type: "person", People: []Person{{ID: "6b5a1b0e-7c3f-4d3c-9d8e-2f1a4b5c6d7e", Name: "Lewis"}}},
+		},
+		{
+			name: "no schema falls back to text",
+			raw:  `[["Skyblock "],["rework",[["i"]]]]`,
+			want: PropertyValue{Type: "text", Text: "Skyblock rework"},
+		},
+		{
+			name: "malformed value",
+			raw:  `{"not":"cells"}`,
+			typ:  "text",
+			want: PropertyValue{Type: "text"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeProperty(json.RawMessage(tt.raw), tt.typ, users)
+			if !reflect.DeepEqual(got, tt.want) {
+				gj, _ := json.Marshal(got)
+				wj, _ := json.Marshal(tt.want)
+				t.Errorf("decodeProperty() = %s, want %s", gj, wj)
+			}
+		})
+	}
+}
+
+func TestPropertyValueString(t *testing.T) {
+	tests := []struct {
+		v    PropertyValue
+		want string
+	}{
+		{PropertyValue{Type: "date", Date: &DateValue{Start: "2024-05-01", End: "2024-05-03"}}, "2024-05-01/2024-05-03"},
+		{PropertyValue{Type: "date"}, ""},
+		{PropertyValue{Type: "multi_select", Options: []string{"Java", "Bedrock"}}, "Java, Bedrock"},
+		{PropertyValue{Type: "number", Number: ptr(3.0)}, "3"},
+		{PropertyValue{Type: "person", People: []Person{{ID: "a", Name: "Lewis"}, {ID: "b"}}}, "Lewis, b"},
+		{PropertyValue{Type: "relation", Pages: []string{"p1", "p2"}}, "p1, p2"},
+		{PropertyValue{Type: "formula", Text: "=42"}, "=42"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("%s String() = %q, want %q", tt.v.Type, got, tt.want)
+		}
+	}
+}
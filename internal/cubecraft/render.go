@@ -0,0 +1,289 @@
+package cubecraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// maxRenderDepth bounds nesting so a malformed block tree cannot recurse
+// without end.
+const maxRenderDepth = 8
+
+type notionBlock struct {
+	ID         string                     `json:"id"`
+	Type       string                     `json:"type"`
+	Alive      *bool                      `json:"alive"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Format     struct {
+		PageIcon      string `json:"page_icon"`
+		DisplaySource string `json:"display_source"`
+	} `json:"format"`
+	Content []string `json:"content"`
+}
+
+type segment struct {
+	text  string
+	marks [][]string
+}
+
+// richText decodes a Notion rich text property: a list of
+// [text, [[mark, arg], ...]] segments.
+func richText(raw json.RawMessage) []segment {
+	var cells [][]json.RawMessage
+	if err := json.Unmarshal(raw, &cells); err != nil {
+		return nil
+	}
+	out := make([]segment, 0, len(cells))
+	for _, c := range cells {
+		if len(c) == 0 {
+			continue
+		}
+		var seg segment
+		if err := json.Unmarshal(c[0], &seg.text); err != nil {
+			continue
+		}
+		if len(c) > 1 {
+			var marks [][]any
+			_ = json.Unmarshal(c[1], &marks)
+			for _, m := range marks {
+				mark := make([]string, 0, len(m))
+				for _, v := range m {
+					s, _ := v.(string)
+					mark = append(mark, s)
+				}
+				seg.marks = append(seg.marks, mark)
+			}
+		}
+		out = append(out, seg)
+	}
+	return out
+}
+
+func segmentsHTML(segs []segment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		t := strings.ReplaceAll(html.EscapeString(s.text), "\n", "<br>")
+		for _, m := range s.marks {
+			if len(m) == 0 {
+				continue
+			}
+			switch m[0] {
+			case "b":
+				t = "<strong>" + t + "</strong>"
+			case "i":
+				t = "<em>" + t + "</em>"
+			case "s":
+				t = "<s>" + t + "</s>"
+			case "_":
+				t = "<u>" + t + "</u>"
+			case "c":
+				t = "<code>" + t + "</code>"
+			case "a":
+				if len(m) > 1 {
+					if href, ok := safeURL(m[1]); ok {
+						t = `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">` + t + "</a>"
+					}
+				}
+			}
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+func segmentsText(segs []segment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		b.WriteString(s.text)
+	}
+	return b.String()
+}
+
+// safeURL allows only absolute http(s) and mailto links.
+func safeURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.String(), u.Host != ""
+	case "mailto":
+		return u.String(), true
+	}
+	return "", false
+}
+
+// renderer turns a page's block tree into HTML and plain text. All text is
+// escaped and every URL checked, so the HTML is safe to embed as is.
+type renderer struct {
+	blocks map[string]notionBlock
+	seen   map[string]bool
+	html   strings.Builder
+	text   strings.Builder
+}
+
+func renderPage(pageID string, blocks map[string]notionBlock) Content {
+	page, ok := blocks[pageID]
+	if !ok {
+		return Content{}
+	}
+	r := &renderer{blocks: blocks, seen: map[string]bool{pageID: true}}
+	r.children(page.Content, 0)
+	return Content{
+		HTML: strings.TrimSpace(r.html.String()),
+		Text: strings.TrimSpace(r.text.String()),
+	}
+}
+
+func (r *renderer) children(ids []string, depth int) {
+	if depth > maxRenderDepth {
+		return
+	}
+	list, n := "", 0
+	closeList := func() {
+		if list != "" {
+			r.html.WriteString("</" + list + ">")
+			list = ""
+		}
+	}
+	for _, id := range ids {
+		b, ok := r.blocks[id]
+		if !ok || r.seen[id] || (b.Alive != nil && !*b.Alive) {
+			continue
+		}
+		r.seen[id] = true
+
+		want := ""
+		switch b.Type {
+		case "bulleted_list", "to_do":
+			want = "ul"
+		case "numbered_list":
+			want = "ol"
+		}
+		if want != list {
+			closeList()
+			if want != "" {
+				r.html.WriteString("<" + want + ">")
+				list, n = want, 0
+			}
+		}
+		n++
+		r.block(b, depth, n)
+	}
+	closeList()
+}
+
+func (r *renderer) block(b notionBlock, depth, n int) {
+	title := richText(b.Properties["title"])
+	h, t := segmentsHTML(title), segmentsText(title)
+	indent := strings.Repeat("  ", depth)
+
+	switch b.Type {
+	case "header", "sub_header", "sub_sub_header":
+		tag := map[string]string{"header": "h2", "sub_header": "h3", "sub_sub_header": "h4"}[b.Type]
+		fmt.Fprintf(&r.html, "<%s>%s</%s>", tag, h, tag)
+		r.line(indent + t)
+	case "bulleted_list", "numbered_list", "to_do":
+		prefix := "- "
+		switch b.Type {
+		case "numbered_list":
+			prefix = fmt.Sprintf("%d. ", n)
+		case "to_do":
+			box, checked := "[ ] ", ""
+			if segmentsText(richText(b.Properties["checked"])) == "Yes" {
+				box, checked = "[x] ", " checked"
+			}
+			prefix += box
+			h = `<input type="checkbox" disabled` + checked + `> ` + h
+		}
+		r.html.WriteString("<li>" + h)
+		r.line(indent + prefix + t)
+		r.children(b.Content, depth+1)
+		r.html.WriteString("</li>")
+		return
+	case "callout":
+		icon := ""
+		if b.Format.PageIcon != "" && !strings.Contains(b.Format.PageIcon, "/") {
+			icon = `<span class="callout-icon">` + html.EscapeString(b.Format.PageIcon) + "</span>"
+		}
+		r.html.WriteString(`<aside class="callout">` + icon + "<div>" + h)
+		r.line(indent + strings.TrimSpace(b.Format.PageIcon+" "+t))
+		r.children(b.Content, depth+1)
+		r.html.WriteString("</div></aside>")
+		return
+	case "quote":
+		r.html.WriteString("<blockquote>" + h + "</blockquote>")
+		r.line(indent + "> " + t)
+	case "code":
+		lang := strings.ToLower(segmentsText(richText(b.Properties["language"])))
+		class := ""
+		if lang != "" {
+			class = ` class="language-` + html.EscapeString(strings.ReplaceAll(lang, " ", "-")) + `"`
+		}
+		r.html.WriteString("<pre><code" + class + ">" + html.EscapeString(t) + "</code></pre>")
+		r.line(t)
+	case "image":
+		src := b.Format.DisplaySource
+		if src == "" {
+			src = segmentsText(richText(b.Properties["source"]))
+		}
+		caption := richText(b.Properties["caption"])
+		if u, ok := imageURL(src, b.ID); ok {
+			alt := html.EscapeString(segmentsText(caption))
+			r.html.WriteString(`<figure><img src="` + html.EscapeString(u) + `" alt="` + alt + `" loading="lazy">`)
+			if len(caption) > 0 {
+				r.html.WriteString("<figcaption>" + segmentsHTML(caption) + "</figcaption>")
+			}
+			r.html.WriteString("</figure>")
+		}
+		if c := segmentsText(caption); c != "" {
+			r.line(indent + c)
+		}
+	case "divider":
+		r.html.WriteString("<hr>")
+	case "bookmark":
+		link := segmentsText(richText(b.Properties["link"]))
+		if u, ok := safeURL(link); ok {
+			label := html.EscapeString(link)
+			if t != "" {
+				label = h
+			}
+			r.html.WriteString(`<p><a href="` + html.EscapeString(u) + `" rel="nofollow noopener noreferrer" target="_blank">` + label + "</a></p>")
+			r.line(indent + link)
+		}
+	case "text", "toggle":
+		if t != "" {
+			r.html.WriteString("<p>" + h + "</p>")
+			r.line(indent + t)
+		}
+	default:
+		// Unsupported block types (embeds, tables, databases) are skipped
+		// but their text, if any, is kept.
+		if t != "" {
+			r.html.WriteString("<p>" + h + "</p>")
+			r.line(indent + t)
+		}
+	}
+	r.children(b.Content, depth+1)
+}
+
+func (r *renderer) line(s string) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	r.text.WriteString(s)
+	r.text.WriteByte('\n')
+}
+
+// imageURL resolves an image source. Files uploaded to Notion are only
+// reachable through its image proxy.
+func imageURL(src, blockID string) (string, bool) {
+	if strings.HasPrefix(src, "attachment:") || strings.Contains(src, "amazonaws.com") {
+		return "https://www.notion.so/image/" + url.QueryEscape(src) + "?table=block&id=" + url.QueryEscape(blockID), true
+	}
+	return safeURL(src)
+}
@@ -0,0 +1,272 @@
+package cubecraft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"roadmapapi/internal/tracing"
+	"roadmapapi/internal/upstream"
+)
+
+const (
+	ProblemMissing     = "missing"
+	ProblemRenamed     = "renamed"
+	ProblemTypeChanged = "type_changed"
+)
+
+// SchemaProperty is one property of the live collection schema. Field is
+// set for properties the board maps.
+type SchemaProperty struct {
+	Key   string `json:"key"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Field string `json:"field,omitempty"`
+}
+
+// SchemaProblem is a mapped property the live schema no longer matches.
+type SchemaProblem struct {
+	Field    string `json:"field"`
+	Problem  string `json:"problem"`
+	Name     string `json:"name,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (p SchemaProblem) String() string {
+	switch p.Problem {
+	case ProblemMissing:
+		return fmt.Sprintf("%s: property %q (key %q) not found", p.Field, p.Name, p.Key)
+	case ProblemRenamed:
+		return fmt.Sprintf("%s: property %q is now named %q", p.Field, p.Expected, p.Actual)
+	case ProblemTypeChanged:
+		return fmt.Sprintf("%s: property %q changed type from %s to %s", p.Field, p.Name, p.Expected, p.Actual)
+	}
+	return p.Field + ": " + p.Problem
+}
+
+// Schema is the collection schema as of the last fetch and how the board's
+// fields were resolved against it.
+type Schema struct {
+	CollectionID string            `json:"collectionId"`
+	FetchedAt    time.Time         `json:"fetchedAt"`
+	Error        string            `json:"error,omitempty"`
+	Fields       map[string]string `json:"fields"`
+	Properties   []SchemaProperty  `json:"properties"`
+	Problems     []SchemaProblem   `json:"problems,omitempty"`
+}
+
+type schemaEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Schema returns the collection schema, reading it first if no fetch has
+// done so yet.
+func (c *Client) Schema(ctx context.Context) *Schema {
+	c.mu.RLock()
+	s := c.schema
+	c.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+	c.resolveSchema(ctx)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.schema
+}
+
+// SchemaProblems describes what the last schema read found wrong with the
+// board mapping.
+func (c *Client) SchemaProblems() []string {
+	c.mu.RLock()
+	s := c.schema
+	c.mu.RUnlock()
+	if s == nil {
+		return nil
+	}
+	var out []string
+	if s.Error != "" {
+		out = append(out, "reading schema failed: "+s.Error)
+	}
+	for _, p := range s.Problems {
+		out = append(out, p.String())
+	}
+	return out
+}
+
+// keys returns the field to property key mapping in effect.
+func (c *Client) keys() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.schema != nil {
+		return c.schema.Fields
+	}
+	return c.board.staticKeys()
+}
+
+// resolveSchema reads the collection schema and resolves the board's fields
+// against it. When the schema cannot be read the previous mapping stays in
+// effect. New problems are logged once.
+func (c *Client) resolveSchema(ctx context.Context) map[string]string {
+	ctx, span := tracing.Start(ctx, "cubecraft.schema")
+	defer span.End()
+
+	live, err := c.fetchSchema(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev := c.schema
+	if err != nil {
+		span.RecordError(err)
+		c.logger.WarnContext(ctx, "reading notion schema failed", "source", c.source, "error", err)
+		s := &Schema{CollectionID: c.board.CollectionID, FetchedAt: time.Now(), Error: err.Error(), Fields: c.board.staticKeys()}
+		if prev != nil {
+			s.Fields, s.Properties, s.Problems = prev.Fields, prev.Properties, prev.Problems
+		}
+		c.schema = s
+		return s.Fields
+	}
+
+	s := c.board.resolve(live, c.seenTypes)
+	s.FetchedAt = time.Now()
+	for f, k := range s.Fields {
+		if _, ok := c.seenTypes[f]; !ok && live[k].Type != "" {
+			c.seenTypes[f] = live[k].Type
+		}
+	}
+	known := make(map[string]bool)
+	if prev != nil {
+		for _, p := range prev.Problems {
+			known[p.String()] = true
+		}
+		for f, k := range s.Fields {
+			if old := prev.Fields[f]; old != "" && old != k {
+				c.logger.InfoContext(ctx, "notion property key changed", "source", c.source, "field", f, "from", old, "to", k)
+			}
+		}
+	}
+	for _, p := range s.Problems {
+		if !known[p.String()] {
+			c.logger.WarnContext(ctx, "notion schema problem", "source", c.source, "field", p.Field, "problem", p.Problem, "detail", p.String())
+		}
+	}
+	span.SetAttrs(tracing.Int("cubecraft.schema_problems", len(s.Problems)))
+	c.schema = s
+	return s.Fields
+}
+
+// resolve maps the board's fields onto the live schema. A property's type
+// is checked against its configured Type, or else against the type first
+// seen for the field.
+func (b Board) resolve(live map[string]schemaEntry, seenTypes map[string]string) *Schema {
+	byName := make(map[string]string, len(live))
+	for k, e := range live {
+		byName[strings.ToLower(strings.TrimSpace(e.Name))] = k
+	}
+	s := &Schema{CollectionID: b.CollectionID, Fields: make(map[string]string)}
+	fields := b.fields()
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	for _, f := range names {
+		p := fields[f]
+		key := ""
+		if p.Name != "" {
+			key = byName[strings.ToLower(strings.TrimSpace(p.Name))]
+		}
+		if key == "" && p.Key != "" {
+			if e, ok := live[p.Key]; ok {
+				key = p.Key
+				if p.Name != "" {
+					s.Problems = append(s.Problems, SchemaProblem{Field: f, Problem: ProblemRenamed, Key: key, Name: e.Name, Expected: p.Name, Actual: e.Name})
+				}
+			}
+		}
+		if key == "" {
+			s.Problems = append(s.Problems, SchemaProblem{Field: f, Problem: ProblemMissing, Name: p.Name, Key: p.Key})
+			if p.Key != "" {
+				s.Fields[f] = p.Key
+			}
+			continue
+		}
+		s.Fields[f] = key
+		want := p.Type
+		if want == "" {
+			want = seenTypes[f]
+		}
+		if got := live[key].Type; want != "" && got != want {
+			s.Problems = append(s.Problems, SchemaProblem{Field: f, Problem: ProblemTypeChanged, Key: key, Name: live[key].Name, Expected: want, Actual: got})
+		}
+	}
+
+	field := make(map[string]string, len(s.Fields))
+	for f, k := range s.Fields {
+		field[k] = f
+	}
+	for k, e := range live {
+		s.Properties = append(s.Properties, SchemaProperty{Key: k, Name: e.Name, Type: e.Type, Field: field[k]})
+	}
+	sort.Slice(s.Properties, func(i, j int) bool { return s.Properties[i].Name < s.Properties[j].Name })
+	return s
+}
+
+// fetchSchema loads the collection record with syncRecordValues and returns
+// its schema by property key.
+func (c *Client) fetchSchema(ctx context.Context) (map[string]schemaEntry, error) {
+	records, err := c.syncRecords(ctx, "collection", []string{c.board.CollectionID})
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := records[c.board.CollectionID]
+	if !ok {
+		return nil, fmt.Errorf("cubecraft: collection %s not returned", c.board.CollectionID)
+	}
+	var rec struct {
+		Value struct {
+			Schema map[string]schemaEntry `json:"schema"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("cubecraft: decode collection: %w", err)
+	}
+	if len(rec.Value.Schema) == 0 {
+		return nil, fmt.Errorf("cubecraft: collection %s has no schema", c.board.CollectionID)
+	}
+	return rec.Value.Schema, nil
+}
+
+// schemaError reports a schema the board cannot be loaded with: one that no
+// longer has the property the board is grouped by.
+func (c *Client) schemaError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.schema == nil {
+		return nil
+	}
+	for _, p := range c.schema.Problems {
+		if p.Field == statusField && p.Problem == ProblemMissing {
+			return &upstream.Error{Kind: upstream.KindSchemaChanged, Source: c.source, Endpoint: "syncRecordValues", Detail: p.String()}
+		}
+	}
+	return nil
+}
+
+// liveProperties returns the schema properties by key, as of the last
+// schema read.
+func (c *Client) liveProperties() map[string]SchemaProperty {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]SchemaProperty)
+	if c.schema != nil {
+		for _, p := range c.schema.Properties {
+			out[p.Key] = p
+		}
+	}
+	return out
+}
@@ -2,32 +2,39 @@ package cubecraft
 
 import (
 	"context"
-	"roadmapapi/internal/hive"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
-)
-
-var columnToStatus = map[string][]string{
-	"in-progress": {"In Progress"},
-	"coming-next": {"Testing"},
-	"released":    {"Released"},
-}
 
-func Columns() map[string]string {
-	return map[string]string{
-		"in-progress": "notion:in-progress",
-		"coming-next": "notion:coming-next",
-		"released":    "notion:released",
-	}
-}
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/roadmap"
+	"roadmapapi/internal/tracing"
+)
 
 type Service interface {
-	Page(ctx context.Context, column string, page, limit int, sortBy string) (hive.RoadmapPage, error)
-	All(ctx context.Context, column string, limit int, sortBy string) ([]hive.RoadmapPage, error)
+	Page(ctx context.Context, column string, page, limit int, sortBy string) (Page, error)
+	All(ctx context.Context, column string, limit int, sortBy string) ([]Page, error)
 	Columns() map[string]string
+	// NativeColumns maps a column per board group to the group's value.
+	NativeColumns() map[string]string
+	// Name is the source name the board is served under.
+	Name() string
+	Board() Board
 	Updates() []statusChange
+	ExportTracker() TrackerState
+	ResetTracker()
+	// Truncation reports cards the last board fetch could not load.
+	Truncation() *Truncation
+	Schema(ctx context.Context) *Schema
+}
+
+// TrackerState is the change tracker's baseline status per card ID and the
+// change events still inside the 24h window.
+type TrackerState struct {
+	Baselines map[string]string `json:"baselines"`
+	Updates   []statusChange    `json:"updates"`
 }
 
 type service struct {
@@ -45,19 +52,35 @@ func NewService(c *Client) Service {
 	}
 }
 
-func (s *service) Columns() map[string]string { return Columns() }
+func (s *service) Columns() map[string]string {
+	out := make(map[string]string, len(s.client.board.Columns))
+	for col := range s.client.board.Columns {
+		out[col] = "notion:" + col
+	}
+	return out
+}
+
+func (s *service) NativeColumns() map[string]string { return s.client.board.NativeColumns() }
+
+func (s *service) Name() string { return s.client.source }
+
+func (s *service) Board() Board { return s.client.board }
 
-func (s *service) Page(ctx context.Context, column string, page, limit int, sortBy string) (hive.RoadmapPage, error) {
+func (s *service) Truncation() *Truncation { return s.client.Truncation() }
+
+func (s *service) Schema(ctx context.Context) *Schema { return s.client.Schema(ctx) }
+
+func (s *service) Page(ctx context.Context, column string, page, limit int, sortBy string) (Page, error) {
 	allPages, err := s.All(ctx, column, limit, sortBy)
 	if err != nil {
-		return hive.RoadmapPage{}, err
+		return Page{}, err
 	}
 	if page <= 0 {
 		page = 1
 	}
 	if page > len(allPages) {
-		return hive.RoadmapPage{
-			Meta: hive.PageMeta{
+		return Page{
+			Meta: roadmap.PageMeta{
 				Page:         page,
 				Limit:        limit,
 				TotalPages:   len(allPages),
@@ -69,53 +92,64 @@ func (s *service) Page(ctx context.Context, column string, page, limit int, sort
 	return allPages[page-1], nil
 }
 
-func (s *service) All(ctx context.Context, column string, limit int, sortBy string) ([]hive.RoadmapPage, error) {
+func (s *service) All(ctx context.Context, column string, limit int, sortBy string) ([]Page, error) {
+	ctx, span := tracing.Start(ctx, "cubecraft.service.all", tracing.String("cubecraft.column", column))
+	defer span.End()
 	if limit <= 0 {
 		limit = 10
 	}
 	cards, err := s.client.Fetch(ctx)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
+	_, mapSpan := tracing.Start(ctx, "cubecraft.map_cards", tracing.Int("cubecraft.cards", len(cards)))
 
-	targetStatuses, ok := columnToStatus[strings.ToLower(column)]
-	if !ok {
-		targetStatuses = nil
-	}
+	board := s.client.board
+	keys := s.client.keys()
+	live := s.client.liveProperties()
+	targetStatuses, known := board.column(strings.ToLower(column))
 
+	// Every card is tracked, so moves out of the requested column are seen.
+	all := make([]item, 0, len(cards))
 	items := make([]item, 0, len(cards))
+	matched := make([]Card, 0, len(cards))
 	for _, c := range cards {
-		props := renameMap(c.Properties)
-		status := props["status"]
-		if !contains(targetStatuses, status) {
-			continue
-		}
+		props := rename(c.Properties, keys, live)
+		status := props[statusField].String()
 		createdAt := time.Unix(c.CreatedAt/1000, 0)
 		updatedAt := time.Unix(c.UpdatedAt/1000, 0)
-		var releasedAt time.Time
-		if props["releasedAt"] != "" {
-			if t, err := time.Parse("2006-01-02", props["releasedAt"]); err == nil {
-				releasedAt = t
-			}
-		}
-		items = append(items, item{
+		it := item{
 			ID:          c.ID,
 			Slug:        c.ID,
 			Title:       c.Title,
-			Status:      mapStatusLabel(status),
-			Category:    props["category"],
-			Network:     props["network"],
-			ProjectLead: props["projectLead"],
+			Status:      board.Label(status),
+			Category:    props["category"].String(),
+			Network:     props["network"].String(),
+			ProjectLead: props["projectLead"].String(),
 			CreatedAt:   createdAt,
 			UpdatedAt:   updatedAt,
-			ReleasedAt:  releasedAt,
+			ReleasedAt:  props["releasedAt"].Date.StartTime(),
 			URL:         c.URL,
-			ContentHTML: "",
-			ContentText: "",
-		})
+			Properties:  props,
+			value:       status,
+		}
+		all = append(all, it)
+		if slices.Contains(targetStatuses, status) {
+			items = append(items, it)
+			matched = append(matched, c)
+		}
+	}
+	mapSpan.End()
+	s.recordStatusChanges(all)
+
+	contents := s.client.Contents(ctx, matched)
+	for i := range items {
+		c := contents[items[i].ID]
+		items[i].ContentHTML, items[i].ContentText = c.HTML, c.Text
 	}
 
-	sortBy = normalizeSort(sortBy, column)
+	sortBy = board.sortOrder(sortBy, column)
 	sort.SliceStable(items, func(i, j int) bool {
 		switch sortBy {
 		case "releasedat:asc":
@@ -138,24 +172,25 @@ func (s *service) All(ctx context.Context, column string, limit int, sortBy stri
 	})
 
 	total := len(items)
+	if known {
+		metrics.Items.With(s.client.source, strings.ToLower(column)).Set(float64(total))
+	}
 	if total == 0 {
-		return []hive.RoadmapPage{
-			{Meta: hive.PageMeta{Page: 1, Limit: limit, TotalPages: 1, TotalResults: 0}},
+		return []Page{
+			{Meta: roadmap.PageMeta{Page: 1, Limit: limit, TotalPages: 1, TotalResults: 0}},
 		}, nil
 	}
 
-	s.recordStatusChanges(items)
-
-	pages := make([]hive.RoadmapPage, 0, (total+limit-1)/limit)
+	pages := make([]Page, 0, (total+limit-1)/limit)
 	for p, offset := 1, 0; offset < total; p, offset = p+1, offset+limit {
 		end := offset + limit
 		if end > total {
 			end = total
 		}
 		pageItems := items[offset:end]
-		dto := make([]hive.RoadmapItem, 0, len(pageItems))
+		dto := make([]PageItem, 0, len(pageItems))
 		for _, it := range pageItems {
-			dto = append(dto, hive.RoadmapItem{
+			dto = append(dto, PageItem{Item: roadmap.Item{
 				ID:           it.ID,
 				Slug:         it.Slug,
 				Title:        it.Title,
@@ -171,10 +206,10 @@ func (s *service) All(ctx context.Context, column string, limit int, sortBy stri
 				ContentHTML:  it.ContentHTML,
 				ContentText:  it.ContentText,
 				Page:         p,
-			})
+			}, Properties: it.Properties})
 		}
-		pages = append(pages, hive.RoadmapPage{
-			Meta: hive.PageMeta{
+		pages = append(pages, Page{
+			Meta: roadmap.PageMeta{
 				Page:         p,
 				Limit:        limit,
 				TotalPages:   (total + limit - 1) / limit,
@@ -205,13 +240,16 @@ func (s *service) recordStatusChanges(items []item) {
 			continue
 		}
 		if prev != it.Status {
+			event := s.client.board.event(it.value)
 			s.updates = append(s.updates, statusChange{
 				At:   now,
+				Type: event,
 				From: prev,
 				To:   it.Status,
 				Item: it,
 			})
 			s.prevStatus[it.ID] = it.Status
+			metrics.ChangeEvents.With(s.client.source, event).Inc()
 		}
 	}
 }
@@ -229,16 +267,22 @@ func (s *service) Updates() []statusChange {
 	return out
 }
 
-func contains(arr []string, v string) bool {
-	if len(arr) == 0 {
-		return true
-	}
-	for _, s := range arr {
-		if s == v {
-			return true
-		}
+func (s *service) ExportTracker() TrackerState {
+	updates := s.Updates()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	baselines := make(map[string]string, len(s.prevStatus))
+	for id, st := range s.prevStatus {
+		baselines[id] = st
 	}
-	return false
+	return TrackerState{Baselines: baselines, Updates: updates}
+}
+
+func (s *service) ResetTracker() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prevStatus = make(map[string]string)
+	s.updates = s.updates[:0]
 }
 
 func isoOrEmpty(t time.Time) string {
@@ -247,37 +291,3 @@ func isoOrEmpty(t time.Time) string {
 	}
 	return t.Format(time.RFC3339)
 }
-
-func normalizeSort(in, column string) string {
-	in = strings.ToLower(strings.TrimSpace(in))
-	switch in {
-	case "releasedat:asc", "releasedat:desc",
-		"lastupdated:asc", "lastupdated:desc",
-		"createdat:asc", "createdat:desc",
-		"title:asc", "title:desc":
-		return in
-	}
-	switch strings.ToLower(column) {
-	case "released":
-		return "releasedat:desc"
-	case "in-progress", "coming-next":
-		return "lastupdated:desc"
-	default:
-		return "title:asc"
-	}
-}
-
-func mapStatusLabel(notionStatus string) string {
-	switch notionStatus {
-	case "In Progress":
-		return "In Progress"
-	case "Released":
-		return "Released"
-	case "Testing":
-		return "Coming Next..."
-	case "Information":
-		return "Information"
-	default:
-		return notionStatus
-	}
-}
@@ -0,0 +1,215 @@
+package cubecraft
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"roadmapapi/internal/upstream"
+)
+
+// DefaultClientVersion is the notion-client-version header sent with every
+// request unless SessionOptions overrides it.
+const DefaultClientVersion = "23.13.0.5155"
+
+type SessionOptions struct {
+	// Cookie is sent as is; it is ignored when CookieFile is set.
+	Cookie string
+	// CookieFile is read for the cookie, such as a mounted secret. Surrounding
+	// whitespace is trimmed.
+	CookieFile    string
+	ClientVersion string
+	Logger        *slog.Logger
+}
+
+// SessionStatus describes the credentials in use and whether Notion last
+// accepted them.
+type SessionStatus struct {
+	// Source is "file", "config" or "none".
+	Source   string    `json:"source"`
+	File     string    `json:"file,omitempty"`
+	LoadedAt time.Time `json:"loadedAt,omitzero"`
+	// Authorized is false from an auth failure until Notion next accepts
+	// the session.
+	Authorized bool      `json:"authorized"`
+	Error      string    `json:"error,omitempty"`
+	FailedAt   time.Time `json:"failedAt,omitzero"`
+}
+
+// Session holds the cookie and client headers sent to Notion. A cookie read
+// from a file can be reloaded without a restart.
+type Session struct {
+	file          string
+	clientVersion string
+	logger        *slog.Logger
+
+	mu       sync.RWMutex
+	cookie   string
+	modTime  time.Time
+	loadedAt time.Time
+	authErr  *upstream.Error
+	failedAt time.Time
+}
+
+func NewSession(opts SessionOptions) (*Session, error) {
+	s := &Session{
+		file:          opts.CookieFile,
+		clientVersion: opts.ClientVersion,
+		logger:        opts.Logger,
+		cookie:        opts.Cookie,
+		loadedAt:      time.Now(),
+	}
+	if s.clientVersion == "" {
+		s.clientVersion = DefaultClientVersion
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+	if s.file != "" {
+		if err := s.Reload(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Reload reads the cookie file again. Without a file it does nothing. A
+// failed reload keeps the previous cookie.
+func (s *Session) Reload() error {
+	if s.file == "" {
+		return nil
+	}
+	st, err := os.Stat(s.file)
+	if err != nil {
+		return fmt.Errorf("cubecraft: cookie file: %w", err)
+	}
+	b, err := os.ReadFile(s.file)
+	if err != nil {
+		return fmt.Errorf("cubecraft: cookie file: %w", err)
+	}
+	cookie := strings.TrimSpace(string(b))
+	if cookie == "" {
+		return fmt.Errorf("cubecraft: cookie file %s is empty", s.file)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cookie != s.cookie {
+		// The new cookie has not been rejected yet.
+		s.authErr, s.failedAt = nil, time.Time{}
+	}
+	s.cookie, s.modTime, s.loadedAt = cookie, st.ModTime(), time.Now()
+	return nil
+}
+
+func (s *Session) changed() bool {
+	st, err := os.Stat(s.file)
+	if err != nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return st.ModTime().After(s.modTime)
+}
+
+// Watch reloads the cookie file on SIGHUP and, when interval is positive,
+// whenever the file is newer than the loaded cookie. It returns at once
+// when the session has no file.
+func (s *Session) Watch(ctx context.Context, interval time.Duration) {
+	if s.file == "" {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			if !s.changed() {
+				continue
+			}
+		}
+		if err := s.Reload(); err != nil {
+			s.logger.Error("notion cookie reload failed, keeping previous cookie", "cookie_file", s.file, "error", err)
+			continue
+		}
+		s.logger.Info("notion cookie reloaded", "cookie_file", s.file)
+	}
+}
+
+// apply sets the credentials and client headers on a Notion API request.
+func (s *Session) apply(req *http.Request, spaceID string) {
+	s.mu.RLock()
+	cookie := s.cookie
+	s.mu.RUnlock()
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	req.Header.Set("x-notion-space-id", spaceID)
+	req.Header.Set("x-notion-active-user-header", "")
+	req.Header.Set("notion-client-version", s.clientVersion)
+	req.Header.Set("notion-audit-log-platform", "web")
+}
+
+// record notes the outcome of a request that shows whether Notion accepts
+// the session: nil clears an earlier failure, errors other than auth
+// failures are ignored.
+func (s *Session) record(err *upstream.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.authErr, s.failedAt = nil, time.Time{}
+		return
+	}
+	if err.Kind != upstream.KindAuth {
+		return
+	}
+	if s.authErr == nil {
+		s.failedAt = time.Now()
+	}
+	s.authErr = err
+}
+
+// AuthErr returns the last auth failure, or nil while Notion accepts the
+// session.
+func (s *Session) AuthErr() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.authErr == nil {
+		return nil
+	}
+	return s.authErr
+}
+
+func (s *Session) Status() SessionStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := SessionStatus{Source: "config", LoadedAt: s.loadedAt, Authorized: s.authErr == nil, FailedAt: s.failedAt}
+	switch {
+	case s.file != "":
+		st.Source, st.File = "file", s.file
+	case s.cookie == "":
+		st.Source, st.LoadedAt = "none", time.Time{}
+	}
+	if s.authErr != nil {
+		st.Error = s.authErr.Error()
+	}
+	return st
+}
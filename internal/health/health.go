@@ -0,0 +1,335 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/poller"
+)
+
+const (
+	StateOK       = "ok"
+	StateDegraded = "degraded"
+	StateDown     = "down"
+)
+
+type ProbeFunc func(ctx context.Context) (status int, items int, err error)
+
+type Source struct {
+	Name   string
+	Probe  ProbeFunc
+	Poller *poller.Poller
+	// Warnings, if set, reports problems that do not stop the source from
+	// serving but leave its data incomplete, such as upstream schema drift.
+	Warnings func() []string
+	// Auth, if set, reports whether the upstream accepts the source's
+	// credentials.
+	Auth func() AuthStatus
+}
+
+// AuthStatus is whether an upstream last accepted a source's credentials.
+type AuthStatus struct {
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
+	Since time.Time `json:"since,omitzero"`
+}
+
+type Options struct {
+	// ReadyMaxAge is the oldest successful snapshot a source may have for
+	// the instance to count as ready.
+	ReadyMaxAge time.Duration
+	// ProbeTimeout bounds a full round of live upstream probes.
+	ProbeTimeout time.Duration
+	// ProbeCacheTTL is how long probe results are reused, which also caps
+	// how often /health/details can hit upstreams.
+	ProbeCacheTTL time.Duration
+}
+
+type Checker struct {
+	opts    Options
+	sources []Source
+	started time.Time
+
+	mu        sync.Mutex
+	probing   chan struct{}
+	probedAt  time.Time
+	lastProbe map[string]ProbeResult
+}
+
+type ProbeResult struct {
+	OK        bool      `json:"ok"`
+	Status    int       `json:"status"`
+	LatencyMs int64     `json:"latencyMs"`
+	Items     int       `json:"items"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func NewChecker(opts Options, sources ...Source) *Checker {
+	return &Checker{opts: opts, sources: sources, started: time.Now()}
+}
+
+type SourceReadiness struct {
+	State           string         `json:"state"`
+	Ready           bool           `json:"ready"`
+	SnapshotAgeSec  *float64       `json:"snapshotAgeSeconds"`
+	PollIntervalSec float64        `json:"pollIntervalSeconds,omitempty"`
+	Reason          string         `json:"reason,omitempty"`
+	Poller          *poller.Status `json:"poller,omitempty"`
+}
+
+func (c *Checker) readiness(now time.Time) (map[string]SourceReadiness, bool) {
+	out := make(map[string]SourceReadiness, len(c.sources))
+	allReady := true
+	for _, s := range c.sources {
+		r := SourceReadiness{State: StateOK, Ready: true}
+		if s.Poller == nil {
+			r.Reason = "polling disabled; readiness not tracked"
+			out[s.Name] = r
+			continue
+		}
+		st := s.Poller.Status()
+		r.Poller = &st
+		r.PollIntervalSec = st.Interval.Seconds()
+		age := st.SnapshotAge(now)
+		if age >= 0 {
+			secs := age.Seconds()
+			r.SnapshotAgeSec = &secs
+		}
+		switch {
+		case age < 0:
+			r.State, r.Ready = StateDown, false
+			r.Reason = "no successful poll yet"
+			if st.LastError != "" {
+				r.Reason += ": " + st.LastError
+			}
+		case age > c.opts.ReadyMaxAge:
+			r.State, r.Ready = StateDown, false
+			r.Reason = "last successful poll is older than " + c.opts.ReadyMaxAge.String()
+		case st.ConsecutiveFailures > 0 || age > 2*st.Interval:
+			r.State = StateDegraded
+			r.Reason = "serving an older snapshot"
+			if st.LastError != "" {
+				r.Reason += "; last poll failed: " + st.LastError
+			}
+		}
+		if !r.Ready {
+			allReady = false
+		}
+		out[s.Name] = r
+	}
+	return out, allReady
+}
+
+// probe runs every source's live probe concurrently. Results are cached for
+// ProbeCacheTTL, and callers arriving while a round is in flight wait for
+// it instead of starting another.
+func (c *Checker) probe(ctx context.Context) (map[string]ProbeResult, time.Time) {
+	for {
+		c.mu.Lock()
+		if c.lastProbe != nil && time.Since(c.probedAt) < c.opts.ProbeCacheTTL {
+			res, at := c.lastProbe, c.probedAt
+			c.mu.Unlock()
+			return res, at
+		}
+		if wait := c.probing; wait != nil {
+			c.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, time.Time{}
+			}
+		}
+		c.probing = make(chan struct{})
+		c.mu.Unlock()
+		break
+	}
+
+	pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.ProbeTimeout)
+	defer cancel()
+	results := make(map[string]ProbeResult, len(c.sources))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, s := range c.sources {
+		if s.Probe == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(s Source) {
+			defer wg.Done()
+			start := time.Now()
+			status, items, err := s.Probe(pctx)
+			r := ProbeResult{
+				OK:        err == nil && status >= 200 && status < 300,
+				Status:    status,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Items:     items,
+				CheckedAt: start,
+			}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			mu.Lock()
+			results[s.Name] = r
+			mu.Unlock()
+		}(s)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	c.mu.Lock()
+	c.lastProbe, c.probedAt = results, now
+	close(c.probing)
+	c.probing = nil
+	c.mu.Unlock()
+	return results, now
+}
+
+// Livez only reports that the process is serving requests. It never looks at
+// upstreams, so an upstream outage cannot cause restarts.
+func (c *Checker) Livez(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":        StateOK,
+		"uptimeSeconds": int64(time.Since(c.started).Seconds()),
+	})
+}
+
+func (c *Checker) Readyz(w http.ResponseWriter, _ *http.Request) {
+	sources, ready := c.readiness(time.Now())
+	code := http.StatusOK
+	state := overall(sources)
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{
+		"ready":   ready,
+		"status":  state,
+		"sources": sources,
+	})
+}
+
+// Details always answers 200, so that degraded and down states can be read
+// without being mistaken for a failing endpoint.
+func (c *Checker) Details(w http.ResponseWriter, r *http.Request) {
+	resp, _ := c.details(r.Context())
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Health keeps the contract /health has always had for monitors: the same
+// body as Details, but 503 when a live upstream probe fails or a source is
+// down.
+func (c *Checker) Health(w http.ResponseWriter, r *http.Request) {
+	resp, healthy := c.details(r.Context())
+	code := http.StatusOK
+	if !healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+func (c *Checker) details(ctx context.Context) (map[string]any, bool) {
+	now := time.Now()
+	sources, ready := c.readiness(now)
+	probes, probedAt := c.probe(ctx)
+
+	type sourceDetails struct {
+		SourceReadiness
+		Probe    *ProbeResult `json:"probe,omitempty"`
+		Auth     *AuthStatus  `json:"auth,omitempty"`
+		Warnings []string     `json:"warnings,omitempty"`
+	}
+	warnings := make(map[string][]string)
+	auths := make(map[string]AuthStatus)
+	for _, s := range c.sources {
+		if s.Warnings != nil {
+			warnings[s.Name] = s.Warnings()
+		}
+		if s.Auth != nil {
+			auths[s.Name] = s.Auth()
+		}
+	}
+	names := make([]string, 0, len(sources))
+	for n := range sources {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	out := make(map[string]sourceDetails, len(sources))
+	for _, n := range names {
+		d := sourceDetails{SourceReadiness: sources[n]}
+		if p, ok := probes[n]; ok {
+			d.Probe = &p
+			if !p.OK && d.State == StateOK {
+				d.State = StateDegraded
+				if d.Reason == "" {
+					d.Reason = "live probe failed"
+				}
+			}
+		}
+		if a, ok := auths[n]; ok {
+			d.Auth = &a
+			if !a.OK {
+				// Polls and probes fail for this reason too; name it rather
+				// than the symptom.
+				d.State = worse(d.State, StateDegraded)
+				d.Reason = "upstream rejected the credentials"
+			}
+		}
+		if w := warnings[n]; len(w) > 0 {
+			d.Warnings = w
+			if d.State == StateOK {
+				d.State = StateDegraded
+				d.Reason = "upstream data incomplete; see warnings"
+			}
+		}
+		out[n] = d
+	}
+
+	state := StateOK
+	healthy := true
+	for _, d := range out {
+		state = worse(state, d.State)
+		if d.State == StateDown || (d.Probe != nil && !d.Probe.OK) {
+			healthy = false
+		}
+	}
+	resp := map[string]any{
+		"ok":            state == StateOK,
+		"status":        state,
+		"ready":         ready,
+		"timestamp":     now.Format(time.RFC3339),
+		"uptimeSeconds": int64(now.Sub(c.started).Seconds()),
+		"services":      out,
+	}
+	if !probedAt.IsZero() {
+		resp["probedAt"] = probedAt.Format(time.RFC3339)
+	}
+	return resp, healthy
+}
+
+func overall(sources map[string]SourceReadiness) string {
+	state := StateOK
+	for _, s := range sources {
+		state = worse(state, s.State)
+	}
+	return state
+}
+
+func worse(a, b string) string {
+	rank := map[string]int{StateOK: 0, StateDegraded: 1, StateDown: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
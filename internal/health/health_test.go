@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthStatus(t *testing.T) {
+	ok := func(context.Context) (int, int, error) { return http.StatusOK, 3, nil }
+	failing := func(context.Context) (int, int, error) { return 0, 0, errors.New("connection refused") }
+	tests := []struct {
+		name   string
+		probes []ProbeFunc
+		health int
+	}{
+		{"all probes pass", []ProbeFunc{ok, ok}, http.StatusOK},
+		{"one probe fails", []ProbeFunc{ok, failing}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []Source
+			for i, p := range tt.probes {
+				sources = append(sources, Source{Name: string(rune('a' + i)), Probe: p})
+			}
+			c := NewChecker(Options{ReadyMaxAge: time.Minute, ProbeTimeout: time.Second}, sources...)
+
+			rec := httptest.NewRecorder()
+			c.Health(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+			if rec.Code != tt.health {
+				t.Errorf("/health status = %d, want %d", rec.Code, tt.health)
+			}
+			var body struct {
+				OK       bool                      `json:"ok"`
+				Services map[string]map[string]any `json:"services"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || len(body.Services) != len(sources) {
+				t.Errorf("/health body: %v, %d services", err, len(body.Services))
+			}
+			if body.OK != (tt.health == http.StatusOK) {
+				t.Errorf("/health ok = %v", body.OK)
+			}
+
+			rec = httptest.NewRecorder()
+			c.Details(rec, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("/health/details status = %d, want 200", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/store"
+)
+
+// Observation is one item as seen in a poll.
+type Observation struct {
+	ID       string
+	Title    string
+	Status   string
+	Category string
+	Network  string
+	Upvotes  int
+	ETA      string
+}
+
+// Transition records an item moving between columns. The first transition
+// of every item has an empty From and marks when it was first seen.
+type Transition struct {
+	At     time.Time `json:"at"`
+	From   string    `json:"from,omitempty"`
+	To     string    `json:"to"`
+	Status string    `json:"status,omitempty"`
+}
+
+// ETAChange records an item's ETA being set, changed or, with an empty ETA,
+// removed.
+type ETAChange struct {
+	At  time.Time `json:"at"`
+	ETA string    `json:"eta,omitempty"`
+}
+
+// UpvoteSample is an item's upvote count from the poll at At. Samples are
+// only recorded when the count changes.
+type UpvoteSample struct {
+	At    time.Time `json:"at"`
+	Count int       `json:"count"`
+}
+
+type Item struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Column      string         `json:"column"`
+	Status      string         `json:"status"`
+	Category    string         `json:"category,omitempty"`
+	Network     string         `json:"network,omitempty"`
+	Upvotes     int            `json:"upvotes"`
+	ETA         string         `json:"eta,omitempty"`
+	FirstSeen   time.Time      `json:"firstSeen"`
+	LastSeen    time.Time      `json:"lastSeen"`
+	Transitions []Transition   `json:"transitions"`
+	ETAChanges  []ETAChange    `json:"etaChanges,omitempty"`
+	UpvoteLog   []UpvoteSample `json:"upvoteLog,omitempty"`
+}
+
+// UpvotesAt returns the upvote count in effect at t, or the earliest known
+// count and its time when t predates every sample.
+func (it *Item) UpvotesAt(t time.Time) (int, time.Time) {
+	if len(it.UpvoteLog) == 0 {
+		return it.Upvotes, it.LastSeen
+	}
+	s := it.UpvoteLog[0]
+	for _, u := range it.UpvoteLog[1:] {
+		if u.At.After(t) {
+			break
+		}
+		s = u
+	}
+	return s.Count, s.At
+}
+
+// ReleasedAt returns when the item last moved into column, if it is there
+// now and the move was observed rather than part of the baseline.
+func (it *Item) ReleasedAt(column string, sourceStart time.Time) (time.Time, bool) {
+	if it.Column != column || len(it.Transitions) == 0 {
+		return time.Time{}, false
+	}
+	t := it.Transitions[len(it.Transitions)-1]
+	if len(it.Transitions) == 1 && it.Baseline(sourceStart) {
+		return time.Time{}, false
+	}
+	return t.At, true
+}
+
+// Baseline reports whether the item was first seen in the very first
+// recorded poll of its source. Such items were already wherever they were
+// found, so their first transition says nothing about when they got there.
+func (it *Item) Baseline(sourceStart time.Time) bool {
+	return !it.FirstSeen.After(sourceStart)
+}
+
+type sourceDoc struct {
+	Start time.Time        `json:"start"`
+	Items map[string]*Item `json:"items"`
+}
+
+// Recorder keeps per-source item histories in memory and persists each
+// source to the store after every observation.
+type Recorder struct {
+	store     *store.Store
+	retention time.Duration
+
+	mu      sync.RWMutex
+	sources map[string]*sourceDoc
+	version uint64
+}
+
+func NewRecorder(st *store.Store, retention time.Duration, sources ...string) (*Recorder, error) {
+	r := &Recorder{
+		store:     st,
+		retention: retention,
+		sources:   make(map[string]*sourceDoc, len(sources)),
+	}
+	for _, s := range sources {
+		doc := &sourceDoc{}
+		if _, err := st.Load(docName(s), doc); err != nil {
+			return nil, err
+		}
+		if doc.Items == nil {
+			doc.Items = make(map[string]*Item)
+		}
+		r.sources[s] = doc
+	}
+	return r, nil
+}
+
+func docName(source string) string { return "history-" + source }
+
+// Observe merges one complete poll of a source, keyed by column, into its
+// history. Recording whole polls keeps the first one a consistent baseline.
+func (r *Recorder) Observe(source string, columns map[string][]Observation, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, ok := r.sources[source]
+	if !ok {
+		return fmt.Errorf("history: unknown source %q", source)
+	}
+	if doc.Start.IsZero() {
+		doc.Start = at
+	}
+	for column, obs := range columns {
+		for _, o := range obs {
+			it := doc.Items[o.ID]
+			if it == nil {
+				it = &Item{ID: o.ID, FirstSeen: at}
+				it.Transitions = append(it.Transitions, Transition{At: at, To: column, Status: o.Status})
+				if o.ETA != "" {
+					it.ETAChanges = append(it.ETAChanges, ETAChange{At: at, ETA: o.ETA})
+				}
+				it.UpvoteLog = append(it.UpvoteLog, UpvoteSample{At: at, Count: o.Upvotes})
+				doc.Items[o.ID] = it
+			} else {
+				if it.Column != column {
+					it.Transitions = append(it.Transitions, Transition{At: at, From: it.Column, To: column, Status: o.Status})
+				}
+				if it.ETA != o.ETA {
+					it.ETAChanges = append(it.ETAChanges, ETAChange{At: at, ETA: o.ETA})
+				}
+				if it.Upvotes != o.Upvotes || len(it.UpvoteLog) == 0 {
+					it.UpvoteLog = append(it.UpvoteLog, UpvoteSample{At: at, Count: o.Upvotes})
+				}
+			}
+			it.Title, it.Column, it.Status = o.Title, column, o.Status
+			it.Category, it.Network = o.Category, o.Network
+			it.Upvotes, it.ETA = o.Upvotes, o.ETA
+			it.LastSeen = at
+		}
+	}
+	if r.retention > 0 {
+		cutoff := at.Add(-r.retention)
+		for id, it := range doc.Items {
+			if it.LastSeen.Before(cutoff) {
+				delete(doc.Items, id)
+				continue
+			}
+			// Keep the last sample before the cutoff: it is the count in
+			// effect at the start of the retained window.
+			if n := sort.Search(len(it.UpvoteLog), func(i int) bool { return !it.UpvoteLog[i].At.Before(cutoff) }); n > 1 {
+				it.UpvoteLog = append(it.UpvoteLog[:0], it.UpvoteLog[n-1:]...)
+			}
+		}
+	}
+	r.version++
+	return r.store.Save(docName(source), doc)
+}
+
+// Version changes whenever any history changes, so derived results can be
+// cached against it.
+func (r *Recorder) Version() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+func (r *Recorder) Sources() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.sources))
+	for s := range r.sources {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Snapshot returns deep copies of a source's items, sorted by ID, and the
+// time of its first recorded poll.
+func (r *Recorder) Snapshot(source string) ([]Item, time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	doc, ok := r.sources[source]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	out := make([]Item, 0, len(doc.Items))
+	for _, it := range doc.Items {
+		out = append(out, it.clone())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, doc.Start, true
+}
+
+// Lookup returns a copy of one item.
+func (r *Recorder) Lookup(source, id string) (Item, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	doc, ok := r.sources[source]
+	if !ok {
+		return Item{}, false
+	}
+	it, ok := doc.Items[id]
+	if !ok {
+		return Item{}, false
+	}
+	return it.clone(), true
+}
+
+func (it *Item) clone() Item {
+	cp := *it
+	cp.Transitions = append([]Transition(nil), it.Transitions...)
+	cp.ETAChanges = append([]ETAChange(nil), it.ETAChanges...)
+	cp.UpvoteLog = append([]UpvoteSample(nil), it.UpvoteLog...)
+	return cp
+}
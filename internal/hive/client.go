@@ -6,12 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/roadmap"
+	"roadmapapi/internal/tracing"
+	"roadmapapi/internal/upstream"
 )
 
 const DefaultBaseURL = "https://updates.playhive.com/api/v1/submission"
@@ -37,6 +43,10 @@ func WithMaxConcurrency(n int) ClientOption {
 	}
 }
 
+func WithLogger(l *slog.Logger) ClientOption {
+	return func(c *Client) { c.logger = l }
+}
+
 type cacheEntry struct {
 	body      []byte
 	expiresAt time.Time
@@ -48,6 +58,31 @@ type Client struct {
 	cache          sync.Map
 	cacheTTL       time.Duration
 	maxConcurrency int
+	logger         *slog.Logger
+}
+
+// PurgeCache drops the cached response for url, or every cached response
+// when url is empty, and returns how many entries were removed.
+func (c *Client) PurgeCache(url string) int {
+	n := 0
+	c.cache.Range(func(k, _ any) bool {
+		if url == "" || k.(string) == url {
+			c.cache.Delete(k)
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// CachedURLs lists the cached upstream URLs with their expiry times.
+func (c *Client) CachedURLs() map[string]time.Time {
+	out := make(map[string]time.Time)
+	c.cache.Range(func(k, v any) bool {
+		out[k.(string)] = v.(cacheEntry).expiresAt
+		return true
+	})
+	return out
 }
 
 func NewClient(baseURL string, hc *http.Client, opts ...ClientOption) *Client {
@@ -56,6 +91,7 @@ func NewClient(baseURL string, hc *http.Client, opts ...ClientOption) *Client {
 		httpClient:     hc,
 		cacheTTL:       0,
 		maxConcurrency: 2,
+		logger:         slog.Default(),
 	}
 	for _, o := range opts {
 		o(c)
@@ -71,6 +107,9 @@ type Query struct {
 	IncludePinned bool
 	Raw           bool
 	BypassCache   bool
+	// Languages are the locales to serve items in, in order of preference.
+	// Items fall back to English.
+	Languages []string
 }
 
 func (q *Query) statusID() (string, error) {
@@ -104,64 +143,156 @@ func (c *Client) buildURL(q Query) (string, error) {
 }
 
 func (c *Client) get(ctx context.Context, fullURL string, bypassCache bool) ([]byte, error) {
+	parent := tracing.SpanFromContext(ctx)
 	if !bypassCache && c.cacheTTL > 0 {
 		if v, ok := c.cache.Load(fullURL); ok {
 			entry := v.(cacheEntry)
 			if time.Now().Before(entry.expiresAt) {
+				metrics.CacheHits.With("hive").Inc()
+				parent.SetAttrs(tracing.String("cache", "hit"))
 				return entry.body, nil
 			}
 			c.cache.Delete(fullURL)
+			metrics.CacheEvictions.With("hive").Inc()
 		}
 	}
+	switch {
+	case c.cacheTTL <= 0:
+		parent.SetAttrs(tracing.String("cache", "disabled"))
+	case bypassCache:
+		metrics.CacheMisses.With("hive").Inc()
+		parent.SetAttrs(tracing.String("cache", "bypass"))
+	default:
+		metrics.CacheMisses.With("hive").Inc()
+		parent.SetAttrs(tracing.String("cache", "miss"))
+	}
+
+	ctx, span := tracing.StartKind(ctx, "GET hive submission", tracing.KindClient,
+		tracing.String("http.request.method", http.MethodGet),
+		tracing.String("url.full", fullURL),
+	)
+	defer span.End()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
+	tracing.Inject(ctx, req.Header)
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.observe(ctx, fullURL, 0, time.Since(start), err)
+		err = upstream.FromTransport("hive", "submission", err)
+		span.RecordError(err)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.observe(ctx, fullURL, resp.StatusCode, time.Since(start), nil)
+	span.SetAttrs(tracing.Int("http.response.status_code", resp.StatusCode))
 	if resp.StatusCode >= 400 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
-		return nil, fmt.Errorf("upstream status %d: %s", resp.StatusCode, string(b))
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		err := upstream.FromStatus("hive", "submission", resp, upstream.Excerpt(b))
+		span.RecordError(err)
+		return nil, err
 	}
 	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
 	if err != nil {
+		err = upstream.FromTransport("hive", "submission", err)
+		span.RecordError(err)
 		return nil, err
 	}
+	span.SetAttrs(tracing.Int("http.response.body.size", len(body)))
+	return body, nil
+}
+
+// store caches a response once it has decoded, so failed responses are
+// fetched again on the next request.
+func (c *Client) store(fullURL string, body []byte, bypassCache bool) {
 	if c.cacheTTL > 0 && !bypassCache {
 		c.cache.Store(fullURL, cacheEntry{
 			body:      body,
 			expiresAt: time.Now().Add(c.cacheTTL),
 		})
 	}
-	return body, nil
 }
 
-func (c *Client) FetchPage(ctx context.Context, q Query) (hiveResponse, []byte, error) {
+// decode reads a submission page; a body without results is an error
+// response rather than an empty column.
+func decode(raw []byte) (hiveResponse, error) {
+	var hr hiveResponse
+	if err := json.Unmarshal(raw, &hr); err != nil {
+		return hiveResponse{}, &upstream.Error{Kind: upstream.KindDecode, Source: "hive", Endpoint: "submission", Err: err}
+	}
+	if hr.Results == nil {
+		return hiveResponse{}, &upstream.Error{Kind: upstream.KindDecode, Source: "hive", Endpoint: "submission", Detail: "response has no results: " + upstream.Excerpt(raw)}
+	}
+	return hr, nil
+}
+
+func (c *Client) observe(ctx context.Context, u string, status int, d time.Duration, err error) {
+	metrics.ObserveUpstream("hive", "submission", status, d)
+	level := slog.LevelDebug
+	if err != nil || status >= 400 {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("source", "hive"),
+		slog.String("url", u),
+		slog.Int("status", status),
+		slog.Duration("duration", d),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(ctx, level, "upstream request", attrs...)
+}
+
+func (c *Client) FetchPage(ctx context.Context, q Query) (hr hiveResponse, raw []byte, err error) {
+	page := q.Page
+	if page <= 0 {
+		page = 1
+	}
+	ctx, span := tracing.Start(ctx, "hive.fetch_page",
+		tracing.String("hive.column", q.Column),
+		tracing.Int("hive.page", page),
+	)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	u, err := c.buildURL(q)
 	if err != nil {
 		return hiveResponse{}, nil, err
 	}
-	raw, err := c.get(ctx, u, q.BypassCache)
+	raw, err = c.get(ctx, u, q.BypassCache)
 	if err != nil {
 		return hiveResponse{}, nil, err
 	}
-	var hr hiveResponse
-	if err := json.Unmarshal(raw, &hr); err != nil {
+	_, decodeSpan := tracing.Start(ctx, "hive.decode_json", tracing.Int("bytes", len(raw)))
+	hr, err = decode(raw)
+	decodeSpan.RecordError(err)
+	decodeSpan.End()
+	if err != nil {
 		return hiveResponse{}, raw, err
 	}
+	c.store(u, raw, q.BypassCache)
+	span.SetAttrs(tracing.Int("hive.results", len(hr.Results)))
 	return hr, raw, nil
 }
 
-func (c *Client) FetchAllPages(ctx context.Context, base Query) ([]hiveResponse, error) {
+func (c *Client) FetchAllPages(ctx context.Context, base Query) (_ []hiveResponse, err error) {
+	ctx, span := tracing.Start(ctx, "hive.fetch_all_pages", tracing.String("hive.column", base.Column))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	first, _, err := c.FetchPage(ctx, base)
 	if err != nil {
 		return nil, err
 	}
 	total := first.TotalPages
+	span.SetAttrs(tracing.Int("hive.total_pages", total))
 	if total == 0 {
 		return []hiveResponse{first}, nil
 	}
@@ -241,27 +372,27 @@ func stripHTML(input string) string {
 	return strings.TrimSpace(replacer.Replace(s))
 }
 
-func MapResponse(hr hiveResponse) RoadmapPage {
-	items := make([]RoadmapItem, 0, len(hr.Results))
+// MapResponse maps a Hive page to roadmap items, localized into the first
+// of langs each item is available in. Without langs items stay in English.
+func MapResponse(hr hiveResponse, langs ...string) roadmap.Page {
+	items := make([]roadmap.Item, 0, len(hr.Results))
 	for _, s := range hr.Results {
 		status := ""
 		if s.PostStatus != nil {
 			status = s.PostStatus.Name
 		}
-		cat := ""
-		if s.PostCategory != nil {
-			if name, ok := s.PostCategory.Name["en"]; ok {
-				cat = name
-			}
+		locale, title, content, cat := s.localize(langs)
+		if len(langs) == 0 {
+			locale = ""
 		}
 		eta := ""
 		if s.Eta != nil {
 			eta = *s.Eta
 		}
-		items = append(items, RoadmapItem{
+		items = append(items, roadmap.Item{
 			ID:           s.ID,
 			Slug:         s.Slug,
-			Title:        s.Title,
+			Title:        title,
 			Status:       status,
 			Category:     cat,
 			Upvotes:      s.Upvotes,
@@ -269,13 +400,14 @@ func MapResponse(hr hiveResponse) RoadmapPage {
 			LastModified: s.LastModified,
 			Pinned:       s.Pinned,
 			ETA:          eta,
-			ContentHTML:  s.ContentHTML,
-			ContentText:  stripHTML(s.ContentHTML),
+			ContentHTML:  content,
+			ContentText:  stripHTML(content),
 			Page:         hr.Page,
+			Language:     locale,
 		})
 	}
-	return RoadmapPage{
-		Meta: PageMeta{
+	return roadmap.Page{
+		Meta: roadmap.PageMeta{
 			Page:         hr.Page,
 			Limit:        hr.Limit,
 			TotalPages:   hr.TotalPages,
@@ -313,21 +445,25 @@ func (c *Client) Probe(ctx context.Context) (int, int, error) {
 			return 0, total, err
 		}
 		req.Header.Set("Accept", "application/json")
+		tracing.Inject(ctx, req.Header)
+		start := time.Now()
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			return 0, total, err
+			c.observe(ctx, u, 0, time.Since(start), err)
+			return 0, total, upstream.FromTransport("hive", "submission", err)
 		}
 		status := resp.StatusCode
+		c.observe(ctx, u, status, time.Since(start), nil)
 		body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
 		resp.Body.Close()
 		if err != nil {
-			return status, total, err
+			return status, total, upstream.FromTransport("hive", "submission", err)
 		}
-		if status >= 400 {
-			return status, total, fmt.Errorf("hive status %d", status)
+		if err := upstream.FromStatus("hive", "submission", resp, upstream.Excerpt(body)); err != nil {
+			return status, total, err
 		}
-		var hr hiveResponse
-		if err := json.Unmarshal(body, &hr); err != nil {
+		hr, err := decode(body)
+		if err != nil {
 			return status, total, err
 		}
 		total += hr.TotalResults
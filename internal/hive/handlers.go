@@ -2,20 +2,40 @@ package hive
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"roadmapapi/internal/problem"
+	"roadmapapi/internal/roadmap"
 )
 
+type HandlerOption func(*Handlers)
+
+// WithArchive enables ?at= on column endpoints.
+func WithArchive(a roadmap.Archive) HandlerOption {
+	return func(h *Handlers) { h.archive = a }
+}
+
 type Handlers struct {
-	svc Service
+	svc     Service
+	archive roadmap.Archive
 }
 
-func NewHandlers(s Service) *Handlers {
-	return &Handlers{svc: s}
+func NewHandlers(s Service, opts ...HandlerOption) *Handlers {
+	h := &Handlers{svc: s}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
 }
 
 func (h *Handlers) Columns(w http.ResponseWriter, _ *http.Request) {
@@ -27,7 +47,18 @@ func (h *Handlers) Columns(w http.ResponseWriter, _ *http.Request) {
 func (h *Handlers) ByColumn(w http.ResponseWriter, r *http.Request) {
 	column := strings.ToLower(chi.URLParam(r, "column"))
 	if err := ValidateColumn(column); err != nil {
-		httpError(w, http.StatusBadRequest, err)
+		problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidColumn, err.Error()).
+			With("columns", slices.Sorted(maps.Keys(columnToStatusID))))
+		return
+	}
+	if r.URL.Query().Has("at") {
+		if col, ok := h.archived(w, r, "hive", column); ok {
+			writeJSON(w, http.StatusOK, flattenPages(col.Pages()))
+		}
+		return
+	}
+	langs, ok := requestLanguages(w, r)
+	if !ok {
 		return
 	}
 	q := Query{
@@ -37,10 +68,11 @@ func (h *Handlers) ByColumn(w http.ResponseWriter, r *http.Request) {
 		IncludePinned: boolFromQuery(r, "includePinned", true),
 		Raw:           false,
 		BypassCache:   !boolFromQuery(r, "cache", true),
+		Languages:     langs,
 	}
 	pages, err := h.svc.GetAll(r.Context(), q)
 	if err != nil {
-		httpError(w, http.StatusBadGateway, err)
+		problem.UpstreamError(w, r, "hive", err)
 		return
 	}
 	all := flattenPages(pages)
@@ -63,9 +95,33 @@ type hiveItemOut struct {
 	LastModifiedUnix int64  `json:"lastModifiedUnix"`
 	URL              string `json:"url,omitempty"`
 	Source           string `json:"source"`
+	Language         string `json:"language,omitempty"`
 }
 
-func flattenPages(pages []RoadmapPage) struct {
+// archived serves column as of ?at= from the archive, setting the
+// X-Snapshot-* headers, or writes the error and returns false.
+func (h *Handlers) archived(w http.ResponseWriter, r *http.Request, source, column string) (roadmap.ArchivedColumn, bool) {
+	col, err := roadmap.LookupArchived(h.archive, source, column, r.URL.Query().Get("at"))
+	switch {
+	case errors.Is(err, roadmap.ErrArchiveDisabled):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeFeatureDisabled, err)
+		return col, false
+	case errors.Is(err, roadmap.ErrInvalidAt):
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err)
+		return col, false
+	case errors.Is(err, roadmap.ErrNotArchived):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, err)
+		return col, false
+	case err != nil:
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, err)
+		return col, false
+	}
+	w.Header().Set("X-Snapshot-Id", col.SnapshotID)
+	w.Header().Set("X-Snapshot-Taken-At", col.TakenAt.UTC().Format(time.RFC3339))
+	return col, true
+}
+
+func flattenPages(pages []roadmap.Page) struct {
 	Items []hiveItemOut `json:"items"`
 } {
 	out := make([]hiveItemOut, 0, 512)
@@ -78,7 +134,7 @@ func flattenPages(pages []RoadmapPage) struct {
 			if t, err := time.Parse(time.RFC3339, it.LastModified); err == nil {
 				lmUnix = t.Unix()
 			}
-			url := "https://updates.playhive.com/en/p/" + it.Slug
+			url := "https://updates.playhive.com/" + itemLanguage(it) + "/p/" + it.Slug
 			out = append(out, hiveItemOut{
 				ID:               it.ID,
 				Slug:             it.Slug,
@@ -95,6 +151,7 @@ func flattenPages(pages []RoadmapPage) struct {
 				LastModifiedUnix: lmUnix,
 				URL:              url,
 				Source:           "hive",
+				Language:         it.Language,
 			})
 		}
 	}
@@ -103,6 +160,44 @@ func flattenPages(pages []RoadmapPage) struct {
 	}{Items: out}
 }
 
+// locale is a language tag such as "de" or "pt-br".
+var locale = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// requestLanguages returns the languages asked for with ?lang= or, failing
+// that, Accept-Language. It writes a problem for a malformed lang.
+func requestLanguages(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	w.Header().Add("Vary", "Accept-Language")
+	param := r.URL.Query().Get("lang")
+	langs := Languages(param, r.Header.Get("Accept-Language"))
+	if param == "" {
+		return slices.DeleteFunc(langs, func(l string) bool { return !locale.MatchString(l) }), true
+	}
+	for _, l := range langs {
+		if !locale.MatchString(l) {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("lang must be a comma-separated list of language tags, got %q", param))
+			return nil, false
+		}
+	}
+	return langs, true
+}
+
+func itemLanguage(it roadmap.Item) string {
+	if it.Language == "" {
+		return DefaultLanguage
+	}
+	base, _, _ := strings.Cut(it.Language, "-")
+	return base
+}
+
+func (h *Handlers) Languages(w http.ResponseWriter, r *http.Request) {
+	langs, err := h.svc.Languages(r.Context())
+	if err != nil {
+		problem.UpstreamError(w, r, "hive", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"default": DefaultLanguage, "languages": langs})
+}
+
 func (h *Handlers) Updates(w http.ResponseWriter, _ *http.Request) {
 	entries := h.svc.Updates()
 	type changeOut struct {
@@ -181,12 +276,6 @@ func strFromQuery(r *http.Request, key, def string) string {
 	return v
 }
 
-func httpError(w http.ResponseWriter, code int, err error) {
-	writeJSON(w, code, map[string]any{
-		"error": err.Error(),
-	})
-}
-
 func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(code)
@@ -0,0 +1,157 @@
+package hive
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language Hive writes submissions in; translations
+// fall back to it.
+const DefaultLanguage = "en"
+
+// translation is one locale of a submission's contentTranslations.
+type translation struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// translations decodes contentTranslations leniently: entries that are not
+// objects are skipped rather than failing the whole page.
+type translations map[string]translation
+
+func (t *translations) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		*t = nil
+		return nil
+	}
+	out := make(translations, len(raw))
+	for locale, v := range raw {
+		var tr translation
+		if err := json.Unmarshal(v, &tr); err != nil || (tr.Title == "" && tr.Content == "") {
+			continue
+		}
+		out[normalizeLocale(locale)] = tr
+	}
+	*t = out
+	return nil
+}
+
+func normalizeLocale(s string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "_", "-"))
+}
+
+// Languages parses a lang parameter (a comma-separated list) or, failing
+// that, an Accept-Language header into locales in order of preference.
+func Languages(param, acceptLanguage string) []string {
+	if param != "" {
+		var out []string
+		for _, l := range strings.Split(param, ",") {
+			if l = normalizeLocale(l); l != "" && !slices.Contains(out, l) {
+				out = append(out, l)
+			}
+		}
+		return out
+	}
+	type pref struct {
+		locale string
+		q      float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalizeLocale(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			prefs = append(prefs, pref{tag, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	out := make([]string, 0, len(prefs))
+	for _, p := range prefs {
+		if !slices.Contains(out, p.locale) {
+			out = append(out, p.locale)
+		}
+	}
+	return out
+}
+
+// pickLocale returns the first preferred locale, or its base language such
+// as "de" for "de-at", that has reports as available. It falls back to
+// DefaultLanguage.
+func pickLocale(prefs []string, has func(string) bool) string {
+	for _, p := range prefs {
+		if p == DefaultLanguage || has(p) {
+			return p
+		}
+		base, _, _ := strings.Cut(p, "-")
+		if base == DefaultLanguage || has(base) {
+			return base
+		}
+	}
+	return DefaultLanguage
+}
+
+// localize returns the submission's title, HTML content and category name
+// in the first preferred locale it has, with English for anything missing.
+func (s hiveSubmission) localize(prefs []string) (locale, title, content, category string) {
+	title, content = s.Title, s.ContentHTML
+	var names map[string]string
+	if s.PostCategory != nil {
+		names = make(map[string]string, len(s.PostCategory.Name))
+		for l, n := range s.PostCategory.Name {
+			names[normalizeLocale(l)] = n
+		}
+		category = names[DefaultLanguage]
+	}
+	locale = pickLocale(prefs, func(l string) bool {
+		_, ok := s.Translations[l]
+		return ok || names[l] != ""
+	})
+	if tr, ok := s.Translations[locale]; ok {
+		if tr.Title != "" {
+			title = tr.Title
+		}
+		if tr.Content != "" {
+			content = tr.Content
+		}
+	}
+	if n := names[locale]; n != "" {
+		category = n
+	}
+	return locale, title, content, category
+}
+
+// locales counts the submissions available in each locale, English
+// included.
+func locales(pages []hiveResponse) map[string]int {
+	out := map[string]int{}
+	for _, hr := range pages {
+		for _, s := range hr.Results {
+			seen := map[string]bool{DefaultLanguage: true}
+			for l := range s.Translations {
+				seen[l] = true
+			}
+			if s.PostCategory != nil {
+				for l := range s.PostCategory.Name {
+					seen[normalizeLocale(l)] = true
+				}
+			}
+			for l := range seen {
+				out[l]++
+			}
+		}
+	}
+	return out
+}
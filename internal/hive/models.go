@@ -1,5 +1,7 @@
 package hive
 
+import "roadmapapi/internal/roadmap"
+
 type hiveResponse struct {
 	Results      []hiveSubmission `json:"results"`
 	Page         int              `json:"page"`
@@ -20,7 +22,7 @@ type hiveSubmission struct {
 	Eta          *string        `json:"eta,omitempty"`
 	PostStatus   *postStatus    `json:"postStatus,omitempty"`
 	PostCategory *postCategory  `json:"postCategory,omitempty"`
-	Translations map[string]any `json:"contentTranslations,omitempty"`
+	Translations translations   `json:"contentTranslations,omitempty"`
 	User         map[string]any `json:"user,omitempty"`
 }
 
@@ -32,45 +34,14 @@ type postCategory struct {
 	Name map[string]string `json:"name"`
 }
 
-type RoadmapItem struct {
-	ID           string `json:"id"`
-	Slug         string `json:"slug"`
-	Title        string `json:"title"`
-	Status       string `json:"status"`
-	Category     string `json:"category"`
-	Upvotes      int    `json:"upvotes"`
-	Date         string `json:"date"`
-	LastModified string `json:"lastModified"`
-	Pinned       bool   `json:"pinned"`
-	ETA          string `json:"eta,omitempty"`
-	ContentHTML  string `json:"contentHtml"`
-	ContentText  string `json:"contentText"`
-	Page         int    `json:"page"`
-	Network      string `json:"network,omitempty"`
-	ProjectLead  string `json:"projectLead,omitempty"`
-	URL          string `json:"url,omitempty"`
-}
-
-type PageMeta struct {
-	Page         int `json:"page"`
-	Limit        int `json:"limit"`
-	TotalPages   int `json:"totalPages"`
-	TotalResults int `json:"totalResults"`
-}
-
-type RoadmapPage struct {
-	Meta  PageMeta      `json:"meta"`
-	Items []RoadmapItem `json:"items"`
-}
-
 type RoadmapAggregate struct {
-	Column string        `json:"column"`
-	Pages  []RoadmapPage `json:"pages"`
+	Column string         `json:"column"`
+	Pages  []roadmap.Page `json:"pages"`
 }
 
 type StatusChange struct {
-	At   int64       `json:"at"`
-	From string      `json:"from"`
-	To   string      `json:"to"`
-	Item RoadmapItem `json:"item"`
+	At   int64        `json:"at"`
+	From string       `json:"from"`
+	To   string       `json:"to"`
+	Item roadmap.Item `json:"item"`
 }
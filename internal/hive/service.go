@@ -2,22 +2,44 @@ package hive
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"sync"
 	"time"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/roadmap"
+	"roadmapapi/internal/tracing"
 )
 
 type Service interface {
-	GetPage(ctx context.Context, q Query) (RoadmapPage, []byte, error)
-	GetAll(ctx context.Context, q Query) ([]RoadmapPage, error)
+	GetPage(ctx context.Context, q Query) (roadmap.Page, []byte, error)
+	GetAll(ctx context.Context, q Query) ([]roadmap.Page, error)
 	GetColumns() map[string]string
+	Languages(ctx context.Context) ([]Language, error)
 	Updates() []changeEntry
+	ExportTracker() TrackerState
+	ResetTracker()
+}
+
+// Language is a locale with the number of items available in it.
+type Language struct {
+	Code  string `json:"code"`
+	Items int    `json:"items"`
 }
 
 type changeEntry struct {
-	At   time.Time
-	From string
-	To   string
-	Item RoadmapItem
+	At   time.Time    `json:"at"`
+	From string       `json:"from"`
+	To   string       `json:"to"`
+	Item roadmap.Item `json:"item"`
+}
+
+// TrackerState is the change tracker's baseline status per item ID and the
+// change events still inside the 24h window.
+type TrackerState struct {
+	Baselines map[string]string `json:"baselines"`
+	Updates   []changeEntry     `json:"updates"`
 }
 
 type service struct {
@@ -35,29 +57,44 @@ func NewService(c *Client) Service {
 	}
 }
 
-func (s *service) GetPage(ctx context.Context, q Query) (RoadmapPage, []byte, error) {
+func (s *service) GetPage(ctx context.Context, q Query) (roadmap.Page, []byte, error) {
+	ctx, span := tracing.Start(ctx, "hive.service.get_page", tracing.String("hive.column", q.Column))
+	defer span.End()
 	hr, raw, err := s.client.FetchPage(ctx, q)
 	if err != nil {
-		return RoadmapPage{}, nil, err
+		span.RecordError(err)
+		return roadmap.Page{}, nil, err
 	}
-	page := MapResponse(hr)
-	s.recordChanges(page.Items)
+	_, mapSpan := tracing.Start(ctx, "hive.map_response", tracing.Int("hive.results", len(hr.Results)))
+	page := MapResponse(hr, q.Languages...)
+	mapSpan.End()
+	s.recordChanges(english(hr, page, q).Items)
 	return page, raw, nil
 }
 
-func (s *service) GetAll(ctx context.Context, q Query) ([]RoadmapPage, error) {
+func (s *service) GetAll(ctx context.Context, q Query) ([]roadmap.Page, error) {
+	ctx, span := tracing.Start(ctx, "hive.service.get_all",
+		tracing.String("hive.column", q.Column),
+		tracing.Bool("hive.bypass_cache", q.BypassCache),
+	)
+	defer span.End()
 	all, err := s.client.FetchAllPages(ctx, q)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
-	out := make([]RoadmapPage, 0, len(all))
-	collected := make([]RoadmapItem, 0, 256)
+	_, mapSpan := tracing.Start(ctx, "hive.map_response", tracing.Int("hive.pages", len(all)))
+	out := make([]roadmap.Page, 0, len(all))
+	collected := make([]roadmap.Item, 0, 256)
 	for _, hr := range all {
-		m := MapResponse(hr)
+		m := MapResponse(hr, q.Languages...)
 		out = append(out, m)
-		collected = append(collected, m.Items...)
+		collected = append(collected, english(hr, m, q).Items...)
 	}
+	mapSpan.SetAttrs(tracing.Int("hive.items", len(collected)))
+	mapSpan.End()
 	s.recordChanges(collected)
+	metrics.Items.With("hive", q.Column).Set(float64(len(collected)))
 	return out, nil
 }
 
@@ -65,7 +102,40 @@ func (s *service) GetColumns() map[string]string {
 	return s.client.Columns()
 }
 
-func (s *service) recordChanges(items []RoadmapItem) {
+// english returns page in English for the change tracker, mapping hr again
+// when q asked for another language.
+func english(hr hiveResponse, page roadmap.Page, q Query) roadmap.Page {
+	if len(q.Languages) == 0 {
+		return page
+	}
+	return MapResponse(hr)
+}
+
+// Languages lists the locales items are available in across all columns,
+// English first.
+func (s *service) Languages(ctx context.Context) ([]Language, error) {
+	ctx, span := tracing.Start(ctx, "hive.service.languages")
+	defer span.End()
+	var all []hiveResponse
+	for _, col := range slices.Sorted(maps.Keys(columnToStatusID)) {
+		pages, err := s.client.FetchAllPages(ctx, Query{Column: col, SortBy: "upvotes:desc", IncludePinned: true})
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		all = append(all, pages...)
+	}
+	counts := locales(all)
+	out := []Language{{Code: DefaultLanguage, Items: counts[DefaultLanguage]}}
+	for _, code := range slices.Sorted(maps.Keys(counts)) {
+		if code != DefaultLanguage {
+			out = append(out, Language{Code: code, Items: counts[code]})
+		}
+	}
+	return out, nil
+}
+
+func (s *service) recordChanges(items []roadmap.Item) {
 	now := time.Now()
 	keepAfter := now.Add(-24 * time.Hour)
 	s.mu.Lock()
@@ -91,6 +161,7 @@ func (s *service) recordChanges(items []RoadmapItem) {
 				Item: it,
 			})
 			s.prevStatus[it.ID] = it.Status
+			metrics.ChangeEvents.With("hive", "status_change").Inc()
 		}
 	}
 }
@@ -107,3 +178,23 @@ func (s *service) Updates() []changeEntry {
 	}
 	return out
 }
+
+func (s *service) ExportTracker() TrackerState {
+	updates := s.Updates()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	baselines := make(map[string]string, len(s.prevStatus))
+	for id, st := range s.prevStatus {
+		baselines[id] = st
+	}
+	return TrackerState{Baselines: baselines, Updates: updates}
+}
+
+// ResetTracker forgets all baselines and change events. The next fetch
+// re-seeds the baselines without emitting changes.
+func (s *service) ResetTracker() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prevStatus = make(map[string]string)
+	s.updates = s.updates[:0]
+}
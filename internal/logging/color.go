@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ansiReset  = "\033[0m"
+	ansiGray   = "\033[90m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiBlue   = "\033[34m"
+	ansiWhite  = "\033[37m"
+)
+
+// colorHandler writes compact, human-oriented lines for local TTY use.
+// Level and HTTP status values are coloured the way the old access logger
+// coloured statuses.
+type colorHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Leveler
+	prefix string
+	attrs  string
+}
+
+func newColorHandler(w io.Writer, level slog.Leveler) *colorHandler {
+	return &colorHandler{mu: new(sync.Mutex), w: w, level: level}
+}
+
+func (h *colorHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *colorHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	buf.WriteString(ansiGray + t.Format("15:04:05.000") + ansiReset + " ")
+	buf.WriteString(levelColor(r.Level) + fmt.Sprintf("%-5s", r.Level.String()) + ansiReset + " ")
+	buf.WriteString(r.Message)
+	buf.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&buf, h.prefix, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *colorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var buf bytes.Buffer
+	for _, a := range attrs {
+		writeAttr(&buf, h.prefix, a)
+	}
+	c := *h
+	c.attrs += buf.String()
+	return &c
+}
+
+func (h *colorHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix += name + "."
+	return &c
+}
+
+func writeAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			writeAttr(buf, p, ga)
+		}
+		return
+	}
+	key := prefix + a.Key
+	val := v.String()
+	if v.Kind() == slog.KindDuration {
+		val = v.Duration().Round(time.Microsecond).String()
+	}
+	if val == "" || strings.ContainsAny(val, " \t\n\"=") {
+		val = strconv.Quote(val)
+	}
+	if key == "status" && v.Kind() == slog.KindInt64 {
+		val = statusColor(int(v.Int64())) + val + ansiReset
+	}
+	buf.WriteString(" " + ansiGray + key + "=" + ansiReset + val)
+}
+
+func levelColor(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return ansiRed
+	case l >= slog.LevelWarn:
+		return ansiYellow
+	case l >= slog.LevelInfo:
+		return ansiGreen
+	default:
+		return ansiBlue
+	}
+}
+
+func statusColor(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return ansiGreen
+	case status >= 400 && status < 500:
+		return ansiYellow
+	case status >= 500:
+		return ansiRed
+	default:
+		return ansiWhite
+	}
+}
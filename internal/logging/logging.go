@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"roadmapapi/internal/problem"
+)
+
+const (
+	FormatAuto  = "auto"
+	FormatJSON  = "json"
+	FormatText  = "text"
+	FormatColor = "color"
+)
+
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", s)
+	}
+	return l, nil
+}
+
+// New builds the process logger. The returned LevelVar is shared with the
+// handler so the level can be changed at runtime.
+func New(level, format string, w *os.File) (*slog.Logger, *slog.LevelVar, error) {
+	lv := new(slog.LevelVar)
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, nil, err
+	}
+	lv.Set(l)
+
+	if format == FormatAuto {
+		format = FormatJSON
+		if isTerminal(w) {
+			format = FormatColor
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+	var h slog.Handler
+	switch format {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatColor:
+		h = newColorHandler(w, lv)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(h), lv, nil
+}
+
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := ""
+			if rc := chi.RouteContext(r.Context()); rc != nil {
+				route = rc.RoutePattern()
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			} else if status >= 400 {
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("remote_ip", remoteIP(r)),
+			)
+		})
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// LevelHandler reports the current log level on GET and changes it on PUT
+// or POST with a body of {"level": "debug"} or a ?level= query parameter.
+func LevelHandler(lv *slog.LevelVar, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			raw := r.URL.Query().Get("level")
+			if raw == "" {
+				var body struct {
+					Level string `json:"level"`
+				}
+				if err := json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&body); err != nil {
+					problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeInvalidBody, `expected {"level": "..."}`))
+					return
+				}
+				raw = body.Level
+			}
+			l, err := ParseLevel(raw)
+			if err != nil {
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err)
+				return
+			}
+			prev := lv.Level()
+			lv.Set(l)
+			logger.Warn("log level changed", "from", prev.String(), "to", l.String(),
+				"request_id", middleware.GetReqID(r.Context()))
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			problem.MethodNotAllowed(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"level": lv.Level().String()})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,335 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	DefBuckets      = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	UpstreamBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30}
+)
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.collectors[c.name()]; dup {
+		panic("metrics: duplicate registration of " + c.name())
+	}
+	r.collectors[c.name()] = c
+}
+
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for n := range r.collectors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	cs := make([]collector, 0, len(names))
+	for _, n := range names {
+		cs = append(cs, r.collectors[n])
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		c.write(bw)
+	}
+	_ = bw.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+type desc struct {
+	fqName string
+	help   string
+	labels []string
+}
+
+func (d desc) name() string { return d.fqName }
+
+func (d desc) header(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.fqName, escapeHelp(d.help), d.fqName, typ)
+}
+
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.fqName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (d desc) labelString(values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, l, escapeLabel(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], escapeLabel(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+type series[T any] struct {
+	mu     sync.RWMutex
+	values map[string][]string
+	items  map[string]*T
+}
+
+func (s *series[T]) get(key string, values []string, mk func() *T) *T {
+	s.mu.RLock()
+	it, ok := s.items[key]
+	s.mu.RUnlock()
+	if ok {
+		return it
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if it, ok = s.items[key]; ok {
+		return it
+	}
+	if s.items == nil {
+		s.items = make(map[string]*T)
+		s.values = make(map[string][]string)
+	}
+	it = mk()
+	s.items[key] = it
+	s.values[key] = append([]string(nil), values...)
+	return it
+}
+
+func (s *series[T]) each(fn func(values []string, it *T)) {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.items))
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type pair struct {
+		values []string
+		it     *T
+	}
+	ps := make([]pair, 0, len(keys))
+	for _, k := range keys {
+		ps = append(ps, pair{s.values[k], s.items[k]})
+	}
+	s.mu.RUnlock()
+	for _, p := range ps {
+		fn(p.values, p.it)
+	}
+}
+
+func (s *series[T]) reset() {
+	s.mu.Lock()
+	s.items = nil
+	s.values = nil
+	s.mu.Unlock()
+}
+
+type Counter struct{ bits atomic.Uint64 }
+
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	addFloat(&c.bits, v)
+}
+
+func (c *Counter) Value() float64 { return math.Float64frombits(c.bits.Load()) }
+
+type CounterVec struct {
+	desc
+	s series[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{desc: desc{fqName: name, help: help, labels: labels}}
+	r.register(v)
+	return v
+}
+
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.s.get(v.key(values), values, func() *Counter { return &Counter{} })
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.header(w, "counter")
+	v.s.each(func(values []string, c *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", v.fqName, v.labelString(values), formatFloat(c.Value()))
+	})
+}
+
+type Gauge struct{ bits atomic.Uint64 }
+
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+func (g *Gauge) Add(v float64) { addFloat(&g.bits, v) }
+
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+type GaugeVec struct {
+	desc
+	s series[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{desc: desc{fqName: name, help: help, labels: labels}}
+	r.register(v)
+	return v
+}
+
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.s.get(v.key(values), values, func() *Gauge { return &Gauge{} })
+}
+
+func (v *GaugeVec) Reset() { v.s.reset() }
+
+func (v *GaugeVec) write(w io.Writer) {
+	v.header(w, "gauge")
+	v.s.each(func(values []string, g *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", v.fqName, v.labelString(values), formatFloat(g.Value()))
+	})
+}
+
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{desc: desc{fqName: name, help: help}, fn: fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.fqName, formatFloat(g.fn()))
+}
+
+type Histogram struct {
+	mu      sync.Mutex
+	upper   []float64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+	h.mu.Lock()
+	if i < len(h.buckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+type HistogramVec struct {
+	desc
+	buckets []float64
+	s       series[Histogram]
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	v := &HistogramVec{desc: desc{fqName: name, help: help, labels: labels}, buckets: b}
+	r.register(v)
+	return v
+}
+
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.s.get(v.key(values), values, func() *Histogram {
+		return &Histogram{upper: v.buckets, buckets: make([]uint64, len(v.buckets))}
+	})
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.header(w, "histogram")
+	v.s.each(func(values []string, h *Histogram) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.buckets...)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+		var cum uint64
+		for i, le := range v.buckets {
+			cum += counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.fqName, v.labelString(values, "le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.fqName, v.labelString(values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.fqName, v.labelString(values), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.fqName, v.labelString(values), count)
+	})
+}
+
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		n := math.Float64bits(math.Float64frombits(old) + v)
+		if bits.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
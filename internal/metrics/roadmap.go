@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var Default = NewRegistry()
+
+// There is no webhook delivery series: the service does not deliver
+// webhooks yet, and /admin/webhooks answers 501. Add it together with
+// delivery.
+
+var (
+	HTTPRequests = Default.NewCounterVec("roadmap_http_requests_total",
+		"Inbound HTTP requests by route pattern, method and status.",
+		"route", "method", "status")
+	HTTPDuration = Default.NewHistogramVec("roadmap_http_request_duration_seconds",
+		"Inbound HTTP request latency by route pattern, method and status.",
+		DefBuckets, "route", "method", "status")
+
+	UpstreamRequests = Default.NewCounterVec("roadmap_upstream_requests_total",
+		"Requests sent to upstream roadmap sources by status code (\"error\" for transport failures).",
+		"source", "endpoint", "code")
+	UpstreamDuration = Default.NewHistogramVec("roadmap_upstream_request_duration_seconds",
+		"Upstream request latency.",
+		UpstreamBuckets, "source", "endpoint")
+	UpstreamErrors = Default.NewCounterVec("roadmap_upstream_errors_total",
+		"Upstream requests that failed or returned a status >= 400.",
+		"source", "endpoint")
+
+	CacheHits = Default.NewCounterVec("roadmap_cache_hits_total",
+		"Upstream response cache hits.", "source")
+	CacheMisses = Default.NewCounterVec("roadmap_cache_misses_total",
+		"Upstream response cache misses, including bypassed lookups.", "source")
+	CacheEvictions = Default.NewCounterVec("roadmap_cache_evictions_total",
+		"Upstream response cache entries dropped because they expired.", "source")
+
+	Items = Default.NewGaugeVec("roadmap_items",
+		"Items seen in the most recent full fetch of a column.", "source", "column")
+	ChangeEvents = Default.NewCounterVec("roadmap_change_events_total",
+		"Change events emitted by the change tracker.", "source", "type")
+)
+
+func init() {
+	start := float64(time.Now().Unix())
+	Default.NewGaugeFunc("process_start_time_seconds",
+		"Start time of the process since unix epoch in seconds.",
+		func() float64 { return start })
+	Default.NewGaugeFunc("go_goroutines",
+		"Number of goroutines that currently exist.",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+}
+
+// ObserveUpstream records one upstream round trip. code is the HTTP status,
+// or 0 when the request failed before a response arrived.
+func ObserveUpstream(source, endpoint string, code int, d time.Duration) {
+	label := "error"
+	if code > 0 {
+		label = strconv.Itoa(code)
+	}
+	UpstreamRequests.With(source, endpoint, label).Inc()
+	UpstreamDuration.With(source, endpoint).Observe(d.Seconds())
+	if code == 0 || code >= 400 {
+		UpstreamErrors.With(source, endpoint).Inc()
+	}
+}
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rc := chi.RouteContext(r.Context()); rc != nil {
+			if p := rc.RoutePattern(); p != "" {
+				route = p
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		code := strconv.Itoa(status)
+		HTTPRequests.With(route, r.Method, code).Inc()
+		HTTPDuration.With(route, r.Method, code).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,189 @@
+package poller
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/tracing"
+)
+
+var (
+	runDuration = metrics.Default.NewHistogramVec("roadmap_poller_run_duration_seconds",
+		"Duration of background poll runs by source and outcome.",
+		metrics.UpstreamBuckets, "source", "outcome")
+	lastSuccess = metrics.Default.NewGaugeVec("roadmap_poller_last_success_timestamp_seconds",
+		"Unix time of the last successful poll per source.", "source")
+)
+
+type Func func(ctx context.Context) error
+
+type Status struct {
+	Source              string        `json:"source"`
+	Interval            time.Duration `json:"-"`
+	Running             bool          `json:"running"`
+	LastRun             time.Time     `json:"lastRun,omitzero"`
+	LastSuccess         time.Time     `json:"lastSuccess,omitzero"`
+	LastDuration        time.Duration `json:"-"`
+	LastError           string        `json:"lastError,omitempty"`
+	Runs                int           `json:"runs"`
+	Failures            int           `json:"failures"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+}
+
+// SnapshotAge is how old the last successful poll is, or -1 if none has
+// succeeded yet.
+func (s Status) SnapshotAge(now time.Time) time.Duration {
+	if s.LastSuccess.IsZero() {
+		return -1
+	}
+	return now.Sub(s.LastSuccess)
+}
+
+type Poller struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	fn       Func
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	status  Status
+	trigger chan struct{}
+	quit    chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func New(name string, interval, timeout time.Duration, fn Func, logger *slog.Logger) *Poller {
+	return &Poller{
+		name:     name,
+		interval: interval,
+		timeout:  timeout,
+		fn:       fn,
+		logger:   logger.With("source", name),
+		status:   Status{Source: name, Interval: interval},
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+func (p *Poller) Name() string { return p.name }
+
+// Start runs the first poll immediately and then every interval until Stop
+// is called or ctx is cancelled.
+func (p *Poller) Start(ctx context.Context) {
+	p.mu.Lock()
+	if p.cancel != nil {
+		p.mu.Unlock()
+		return
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.quit = make(chan struct{})
+	p.done = make(chan struct{})
+	p.status.Running = true
+	p.mu.Unlock()
+
+	go p.loop(ctx, p.quit)
+}
+
+func (p *Poller) loop(ctx context.Context, quit <-chan struct{}) {
+	defer close(p.done)
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		p.run(ctx)
+		select {
+		case <-quit:
+			return
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-p.trigger:
+			t.Reset(p.interval)
+		}
+	}
+}
+
+// Trigger requests an immediate poll. It never blocks; a trigger that
+// arrives while one is already pending is coalesced.
+func (p *Poller) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Stop ends the loop and lets an in-flight run finish, so that its result
+// is recorded. The run is only cancelled when ctx expires first.
+func (p *Poller) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	cancel, quit, done := p.cancel, p.quit, p.done
+	p.quit = nil
+	p.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	if quit != nil {
+		close(quit)
+	}
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		cancel()
+		<-done
+	}
+	cancel()
+	p.mu.Lock()
+	p.status.Running = false
+	p.mu.Unlock()
+	return err
+}
+
+func (p *Poller) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *Poller) run(ctx context.Context) {
+	runCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	runCtx, span := tracing.Start(runCtx, "poller.run", tracing.String("source", p.name))
+	defer span.End()
+
+	start := time.Now()
+	err := p.fn(runCtx)
+	d := time.Since(start)
+	if ctx.Err() != nil {
+		return
+	}
+	span.RecordError(err)
+
+	p.mu.Lock()
+	p.status.LastRun = start
+	p.status.LastDuration = d
+	p.status.Runs++
+	if err != nil {
+		p.status.Failures++
+		p.status.ConsecutiveFailures++
+		p.status.LastError = err.Error()
+	} else {
+		p.status.LastSuccess = start
+		p.status.ConsecutiveFailures = 0
+		p.status.LastError = ""
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		runDuration.With(p.name, "error").Observe(d.Seconds())
+		p.logger.Warn("poll failed", "duration", d, "error", err)
+		return
+	}
+	runDuration.With(p.name, "ok").Observe(d.Seconds())
+	lastSuccess.With(p.name).Set(float64(start.Unix()))
+	p.logger.Debug("poll completed", "duration", d)
+}
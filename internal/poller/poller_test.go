@@ -0,0 +1,61 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestStopLetsTheRunFinish(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var runErr error
+	p := New("test", time.Hour, time.Minute, func(ctx context.Context) error {
+		close(started)
+		<-release
+		runErr = ctx.Err()
+		return nil
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Start(context.Background())
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- p.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned %v while the run was in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if runErr != nil {
+		t.Errorf("the run saw %v, want it left to finish", runErr)
+	}
+	if s := p.Status(); s.Runs != 1 || s.LastSuccess.IsZero() || s.Running {
+		t.Errorf("status = %+v, want the run recorded and the poller stopped", s)
+	}
+}
+
+func TestStopCancelsAtTheDeadline(t *testing.T) {
+	started := make(chan struct{})
+	p := New("test", time.Hour, time.Minute, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want the deadline error", err)
+	}
+	if s := p.Status(); s.Runs != 0 || s.Running {
+		t.Errorf("status = %+v, want the cancelled run discarded", s)
+	}
+}
@@ -0,0 +1,180 @@
+// Package problem writes error responses as RFC 7807 problem details with
+// a stable, machine-readable code.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"roadmapapi/internal/upstream"
+)
+
+const ContentType = "application/problem+json"
+
+// Codes for failures of the request itself. Upstream failures use the
+// upstream.Kind values, such as upstream_timeout and upstream_auth.
+const (
+	CodeInvalidColumn    = "invalid_column"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeInvalidBody      = "invalid_body"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeCORSRejected     = "cors_rejected"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeFeatureDisabled  = "feature_disabled"
+	CodeNotImplemented   = "not_implemented"
+	CodeInternal         = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object. Type is derived from
+// Code.
+type Problem struct {
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+	// Source is the roadmap source that failed, if any.
+	Source string `json:"source,omitempty"`
+	// Upstream describes an upstream failure; it is only sent when the
+	// Middleware was built to expose it.
+	Upstream *Upstream `json:"upstream,omitempty"`
+	// Extensions are further members, such as the accepted values.
+	Extensions map[string]any `json:"-"`
+}
+
+type Upstream struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Error    string `json:"error"`
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Code: code, Detail: detail}
+}
+
+// With adds an extension member.
+func (p *Problem) With(key string, v any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = v
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	b, err := json.Marshal((*plain)(p))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]any, len(p.Extensions)+10)
+	maps.Copy(out, p.Extensions)
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	out["type"] = "urn:roadmapapi:problem:" + p.Code
+	return json.Marshal(out)
+}
+
+type exposeKey struct{}
+
+// Middleware marks requests whose problems include upstream details when
+// exposeUpstream is set. Without it upstream failures are only described by
+// their code.
+func Middleware(exposeUpstream bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !exposeUpstream {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), exposeKey{}, true)))
+		})
+	}
+}
+
+func exposing(ctx context.Context) bool {
+	e, _ := ctx.Value(exposeKey{}).(bool)
+	return e
+}
+
+// Write sends p, filling in the request ID and instance.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if r != nil {
+		p.RequestID = middleware.GetReqID(r.Context())
+		if p.Instance == "" {
+			p.Instance = r.URL.Path
+		}
+		if !exposing(r.Context()) {
+			p.Upstream = nil
+		}
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Error sends a problem with err's message as the detail.
+func Error(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	Write(w, r, New(status, code, err.Error()))
+}
+
+// UpstreamError sends the problem for a failed call to source. Upstream
+// responses are only quoted when the Middleware exposes them.
+func UpstreamError(w http.ResponseWriter, r *http.Request, source string, err error) {
+	status, kind, retry := upstream.Response(err)
+	p := New(status, string(kind), upstreamDetail(kind))
+	p.Source = source
+	p.Upstream = &Upstream{Error: err.Error()}
+	if e, ok := upstream.As(err); ok {
+		if e.Source != "" {
+			p.Source = e.Source
+		}
+		p.Upstream.Endpoint, p.Upstream.Status = e.Endpoint, e.Status
+	}
+	if retry > 0 {
+		w.Header().Set("Retry-After", upstream.RetryAfterHeader(retry))
+	}
+	Write(w, r, p)
+}
+
+func upstreamDetail(kind upstream.Kind) string {
+	switch kind {
+	case upstream.KindAuth:
+		return "The upstream rejected this server's credentials."
+	case upstream.KindRateLimited:
+		return "The upstream is rate limiting this server; retry later."
+	case upstream.KindSchemaChanged:
+		return "The upstream data no longer matches this server's mapping."
+	case upstream.KindEmpty:
+		return "The upstream returned no data."
+	case upstream.KindDecode:
+		return "The upstream response could not be decoded."
+	case upstream.KindTimeout:
+		return "The upstream did not respond in time."
+	case upstream.KindUnavailable:
+		return "The upstream could not be reached or failed."
+	}
+	return "The upstream request failed."
+}
+
+// NotFound and MethodNotAllowed answer unrouted requests.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, New(http.StatusNotFound, CodeNotFound, "no endpoint at "+r.URL.Path))
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path))
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token-bucket refill rate and capacity. A zero Rate means
+// unlimited.
+type Limit struct {
+	Rate  float64 // tokens per second
+	Burst int
+}
+
+func PerMinute(n float64, burst int) Limit {
+	return Limit{Rate: n / 60, Burst: burst}
+}
+
+func (l Limit) Unlimited() bool { return l.Rate <= 0 }
+
+// Result describes the outcome of one Allow call.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // until one token is available; zero if allowed
+	Reset      time.Duration // until the bucket is full again
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(max(b.limit.Burst, 1))
+}
+
+func (b *bucket) take(l Limit, now time.Time) Result {
+	burst := float64(max(l.Burst, 1))
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	}
+	b.last = now
+	b.limit = l
+
+	res := Result{Limit: int(burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / l.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((burst - b.tokens) / l.Rate)
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Limiter keeps one bucket per key. Buckets that have been idle long enough
+// to refill completely are indistinguishable from new ones and are dropped
+// by the periodic sweep.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (lm *Limiter) Allow(key string, l Limit) Result {
+	if l.Unlimited() {
+		return Result{Allowed: true}
+	}
+	now := lm.now()
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	if now.Sub(lm.swept) > time.Minute {
+		lm.sweep(now)
+	}
+	b := lm.buckets[key]
+	if b == nil {
+		b = &bucket{}
+		lm.buckets[key] = b
+	}
+	return b.take(l, now)
+}
+
+func (lm *Limiter) sweep(now time.Time) {
+	lm.swept = now
+	for k, b := range lm.buckets {
+		if b.full(now) {
+			delete(lm.buckets, k)
+		}
+	}
+}
+
+func (lm *Limiter) Len() int {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	return len(lm.buckets)
+}
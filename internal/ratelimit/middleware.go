@@ -0,0 +1,237 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/problem"
+)
+
+const (
+	ClassDefault = "default"
+	// ClassCrawl covers requests that fan out over every upstream page.
+	ClassCrawl = "crawl"
+	// ClassBypass covers cache=false requests, which always reach upstream.
+	ClassBypass = "bypass"
+)
+
+var decisions = metrics.Default.NewCounterVec("roadmap_ratelimit_decisions_total",
+	"Per-IP rate limit decisions by route class and result (allowed, limited, allowlisted).",
+	"class", "result")
+
+type Config struct {
+	AllowList []netip.Prefix
+	Default   Limit
+	Crawl     Limit
+	Bypass    Limit
+}
+
+// ParseAllowList accepts plain IPs and CIDR prefixes.
+func ParseAllowList(entries []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+	}
+	return out, nil
+}
+
+// IPLimiter applies token buckets per client IP and route class. Behind a
+// trusted proxy it relies on middleware.RealIP having already rewritten
+// RemoteAddr.
+type IPLimiter struct {
+	cfg     Config
+	limiter *Limiter
+}
+
+// ipLimiters are the limiters the bucket gauge sums over. The gauge is
+// registered once, since a registry rejects duplicate names.
+var (
+	ipLimitersMu sync.Mutex
+	ipLimiters   []*Limiter
+)
+
+func init() {
+	metrics.Default.NewGaugeFunc("roadmap_ratelimit_buckets",
+		"Per-IP rate limit buckets currently tracked.",
+		func() float64 {
+			ipLimitersMu.Lock()
+			defer ipLimitersMu.Unlock()
+			n := 0
+			for _, l := range ipLimiters {
+				n += l.Len()
+			}
+			return float64(n)
+		})
+}
+
+func NewIPLimiter(cfg Config) *IPLimiter {
+	l := &IPLimiter{cfg: cfg, limiter: NewLimiter()}
+	ipLimitersMu.Lock()
+	ipLimiters = append(ipLimiters, l.limiter)
+	ipLimitersMu.Unlock()
+	return l
+}
+
+// Middleware charges every request to the default bucket of its client IP,
+// and additionally to the bypass or crawl bucket for cache=false and all=true
+// requests. Allow-listed IPs skip limiting entirely. A nil IPLimiter disables
+// limiting.
+func (l *IPLimiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if l.allowListed(ip) {
+			decisions.With(ClassDefault, "allowlisted").Inc()
+			next.ServeHTTP(w, r)
+			return
+		}
+		classes := []string{ClassDefault}
+		q := r.URL.Query()
+		if falsy(q.Get("cache")) {
+			classes = append(classes, ClassBypass)
+		}
+		if truthy(q.Get("all")) {
+			classes = append(classes, ClassCrawl)
+		}
+		if l.charge(w, r, ip, classes...) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Crawl guards routes that fan out over every upstream page. Served from
+// cache they cost no more than any other read, so only cache=false requests,
+// which always crawl upstream, are charged to the crawl bucket as well.
+// all=true requests were already charged by Middleware.
+func (l *IPLimiter) Crawl(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		ip := clientIP(r)
+		if !falsy(q.Get("cache")) || truthy(q.Get("all")) || l.allowListed(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if l.charge(w, r, ip, ClassCrawl) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (l *IPLimiter) limit(class string) Limit {
+	switch class {
+	case ClassCrawl:
+		return l.cfg.Crawl
+	case ClassBypass:
+		return l.cfg.Bypass
+	default:
+		return l.cfg.Default
+	}
+}
+
+// charge takes a token from each class bucket and reports whether the
+// request may proceed. The RateLimit-* headers describe the most
+// restrictive bucket; on denial it writes the 429 response itself.
+func (l *IPLimiter) charge(w http.ResponseWriter, r *http.Request, ip string, classes ...string) bool {
+	var tightest *Result
+	for _, class := range classes {
+		lim := l.limit(class)
+		if lim.Unlimited() {
+			continue
+		}
+		res := l.limiter.Allow(class+"|"+ip, lim)
+		if tightest == nil || !res.Allowed || (tightest.Allowed && res.Remaining < tightest.Remaining) {
+			tightest = &res
+		}
+		if !res.Allowed {
+			decisions.With(class, "limited").Inc()
+			SetHeaders(w.Header(), res)
+			w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+			detail := fmt.Sprintf("rate limit exceeded for %s requests; retry in %ss", class, ceilSeconds(res.RetryAfter))
+			problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, detail).With("class", class))
+			return false
+		}
+		decisions.With(class, "allowed").Inc()
+	}
+	if tightest != nil {
+		SetHeaders(w.Header(), *tightest)
+	}
+	return true
+}
+
+func (l *IPLimiter) allowListed(ip string) bool {
+	if len(l.cfg.AllowList) == 0 {
+		return false
+	}
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	a = a.Unmap()
+	for _, p := range l.cfg.AllowList {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// SetHeaders writes the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers for res.
+func SetHeaders(h http.Header, res Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func truthy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "y", "on":
+		return true
+	}
+	return false
+}
+
+func falsy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "0", "false", "no", "n", "off":
+		return true
+	}
+	return false
+}
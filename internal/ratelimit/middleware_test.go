@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCrawlOnlyChargesUncachedCrawls(t *testing.T) {
+	l := NewIPLimiter(Config{
+		Default: PerMinute(1000, 1000),
+		Crawl:   PerMinute(1, 1),
+		Bypass:  PerMinute(1000, 1000),
+	})
+	h := l.Middleware(l.Crawl(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	get := func(target string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := range 5 {
+		if code := get("/hive/in-progress"); code != http.StatusNoContent {
+			t.Fatalf("cached read %d: status %d, want it outside the crawl bucket", i, code)
+		}
+	}
+	if code := get("/hive/in-progress?cache=false"); code != http.StatusNoContent {
+		t.Fatalf("first uncached crawl: status %d", code)
+	}
+	if code := get("/hive/in-progress?cache=false"); code != http.StatusTooManyRequests {
+		t.Fatalf("second uncached crawl: status %d, want 429", code)
+	}
+	if code := get("/hive/in-progress?all=true"); code != http.StatusTooManyRequests {
+		t.Fatalf("all=true with the crawl bucket empty: status %d, want 429", code)
+	}
+	if code := get("/hive/in-progress"); code != http.StatusNoContent {
+		t.Fatalf("cached read after crawls: status %d", code)
+	}
+}
+
+func TestSeveralLimiters(t *testing.T) {
+	// Each limiter used to register its own bucket gauge, and the second
+	// registration panicked.
+	a := NewIPLimiter(Config{Default: PerMinute(10, 10)})
+	b := NewIPLimiter(Config{Default: PerMinute(10, 10)})
+	for _, l := range []*IPLimiter{a, b} {
+		h := l.Middleware(http.NotFoundHandler())
+		req := httptest.NewRequest(http.MethodGet, "/hive/in-progress", nil)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,50 @@
+package roadmap
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotArchived is returned by an Archive with no snapshot old enough.
+	ErrNotArchived = errors.New("no archived snapshot")
+	// ErrArchiveDisabled is returned by LookupArchived without an Archive.
+	ErrArchiveDisabled = errors.New("point-in-time queries are not enabled")
+	// ErrInvalidAt is returned by LookupArchived for a malformed timestamp.
+	ErrInvalidAt = errors.New("at must be an RFC 3339 timestamp")
+)
+
+// Archive answers point-in-time queries from stored snapshots.
+type Archive interface {
+	Column(source, column string, at time.Time) (ArchivedColumn, error)
+}
+
+// ArchivedColumn is a column as stored in a snapshot.
+type ArchivedColumn struct {
+	SnapshotID string
+	TakenAt    time.Time
+	Items      []Item
+}
+
+// LookupArchived looks up column as of at, an RFC 3339 timestamp. It fails
+// with ErrArchiveDisabled when a is nil, ErrInvalidAt when at does not parse
+// and ErrNotArchived when no snapshot is old enough.
+func LookupArchived(a Archive, source, column, at string) (ArchivedColumn, error) {
+	if a == nil {
+		return ArchivedColumn{}, ErrArchiveDisabled
+	}
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return ArchivedColumn{}, fmt.Errorf("%w, got %q", ErrInvalidAt, at)
+	}
+	return a.Column(source, column, t)
+}
+
+// Pages returns the archived items as a single page.
+func (c ArchivedColumn) Pages() []Page {
+	return []Page{{
+		Meta:  PageMeta{Page: 1, Limit: len(c.Items), TotalPages: 1, TotalResults: len(c.Items)},
+		Items: c.Items,
+	}}
+}
@@ -0,0 +1,44 @@
+package roadmap
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeArchive struct{ takenAt time.Time }
+
+func (f fakeArchive) Column(_, _ string, at time.Time) (ArchivedColumn, error) {
+	if at.Before(f.takenAt) {
+		return ArchivedColumn{}, ErrNotArchived
+	}
+	return ArchivedColumn{SnapshotID: "s1", TakenAt: f.takenAt, Items: []Item{{ID: "a"}, {ID: "b"}}}, nil
+}
+
+func TestLookupArchived(t *testing.T) {
+	a := fakeArchive{takenAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name    string
+		a       Archive
+		at      string
+		wantErr error
+	}{
+		{"found", a, "2025-03-02T00:00:00Z", nil},
+		{"disabled", nil, "2025-03-02T00:00:00Z", ErrArchiveDisabled},
+		{"plain date", a, "2025-03-02", ErrInvalidAt},
+		{"missing", a, "", ErrInvalidAt},
+		{"too early", a, "2025-02-01T00:00:00Z", ErrNotArchived},
+	}
+	for _, tt := range tests {
+		col, err := LookupArchived(tt.a, "hive", "released", tt.at)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if p := col.Pages(); len(p) != 1 || p[0].Meta.TotalResults != 2 || len(p[0].Items) != 2 {
+				t.Errorf("%s: pages = %+v, want one page of two items", tt.name, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package roadmap holds the item and page types shared by every roadmap
+// source, the archive and the pollers.
+package roadmap
+
+type Item struct {
+	ID           string `json:"id"`
+	Slug         string `json:"slug"`
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	Category     string `json:"category"`
+	Upvotes      int    `json:"upvotes"`
+	Date         string `json:"date"`
+	LastModified string `json:"lastModified"`
+	Pinned       bool   `json:"pinned"`
+	ETA          string `json:"eta,omitempty"`
+	ContentHTML  string `json:"contentHtml"`
+	ContentText  string `json:"contentText"`
+	Page         int    `json:"page"`
+	Network      string `json:"network,omitempty"`
+	ProjectLead  string `json:"projectLead,omitempty"`
+	URL          string `json:"url,omitempty"`
+	// Language is the locale of the title, content and category when the
+	// source was asked for one.
+	Language string `json:"language,omitempty"`
+}
+
+type PageMeta struct {
+	Page         int `json:"page"`
+	Limit        int `json:"limit"`
+	TotalPages   int `json:"totalPages"`
+	TotalResults int `json:"totalResults"`
+}
+
+type Page struct {
+	Meta  PageMeta `json:"meta"`
+	Items []Item   `json:"items"`
+}
@@ -1,134 +1,116 @@
 package routes
 
 import (
-	"context"
-	"encoding/json"
-	"log"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"roadmapapi/internal/app"
+	"roadmapapi/internal/archive"
+	"roadmapapi/internal/auth"
 	"roadmapapi/internal/cubecraft"
 	"roadmapapi/internal/hive"
+	"roadmapapi/internal/logging"
+	"roadmapapi/internal/metrics"
+	"roadmapapi/internal/problem"
+	"roadmapapi/internal/stats"
+	"roadmapapi/internal/tracing"
 )
 
-func NewRouter() http.Handler {
+func NewRouter(a *app.App) http.Handler {
+	cfg := a.Config
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(colorLogger)
+	r.Use(problem.Middleware(cfg.Server.ExposeUpstreamErrors))
+	if cfg.Server.TrustProxy {
+		r.Use(middleware.RealIP)
+	}
+	r.Use(tracing.Middleware)
+	r.Use(logging.AccessLog(a.Logger))
+	if cfg.Metrics.Enabled {
+		r.Use(metrics.Middleware)
+	}
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
-
-	hiveClient := hive.NewClient(
-		hive.DefaultBaseURL,
-		&http.Client{Timeout: 12 * time.Second},
-		hive.WithCacheTTL(30*time.Second),
-		hive.WithMaxConcurrency(4),
-	)
-	h := hive.NewHandlers(hive.NewService(hiveClient))
-
-	ccClient := cubecraft.NewClient(
-		cubecraft.WithCacheTTL(2 * time.Minute),
-	)
-	cc := cubecraft.NewHandlers(cubecraft.NewService(ccClient))
-
-	r.Get("/health", func(w http.ResponseWriter, req *http.Request) {
-		type serviceHealth struct {
-			OK        bool   `json:"ok"`
-			Status    int    `json:"status"`
-			LatencyMs int64  `json:"latencyMs"`
-			Items     int    `json:"items"`
-			Error     string `json:"error,omitempty"`
-		}
-		ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
-		defer cancel()
-
-		hiveStart := time.Now()
-		hiveStatus, hiveItems, hiveErr := hiveClient.Probe(ctx)
-		hiveRes := serviceHealth{
-			OK:        hiveErr == nil && hiveStatus >= 200 && hiveStatus < 300,
-			Status:    hiveStatus,
-			LatencyMs: time.Since(hiveStart).Milliseconds(),
-			Items:     hiveItems,
-		}
-		if hiveErr != nil {
-			hiveRes.Error = hiveErr.Error()
+	r.Use(a.CORS.Handler)
+	r.Use(a.RateLimit.Middleware)
+	r.Use(middleware.Timeout(cfg.Server.RequestTimeout.Duration))
+	r.Use(a.Auth.Authenticate)
+	r.NotFound(problem.NotFound)
+	r.MethodNotAllowed(problem.MethodNotAllowed)
+
+	r.Get("/livez", a.Health.Livez)
+	r.Get("/readyz", a.Health.Readyz)
+	r.Get("/health/details", a.Health.Details)
+	r.Get("/health", a.Health.Health)
+
+	// Without auth nobody could be told apart from an admin, so the admin
+	// endpoints are not served at all.
+	if a.Auth != nil {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(a.Auth.Require(auth.ScopeAdmin), a.Audit.Middleware)
+			r.Handle("/log-level", logging.LevelHandler(a.LogLevel, a.Logger))
+			r.Route("/keys", a.Auth.Routes)
+			a.Admin.Routes(r)
+		})
+	}
+
+	if cfg.Metrics.Enabled {
+		r.Method(http.MethodGet, cfg.Metrics.Path, metrics.Default.Handler())
+	}
+
+	var st *stats.Handlers
+	if a.History != nil {
+		var statsOpts []stats.HandlerOption
+		if a.Auth != nil {
+			statsOpts = append(statsOpts, stats.WithPrivateCache())
 		}
-
-		notionStart := time.Now()
-		notionStatus, notionItems, notionErr := ccClient.Probe(ctx)
-		notionRes := serviceHealth{
-			OK:        notionErr == nil && notionStatus >= 200 && notionStatus < 300,
-			Status:    notionStatus,
-			LatencyMs: time.Since(notionStart).Milliseconds(),
-			Items:     notionItems,
-		}
-		if notionErr != nil {
-			notionRes.Error = notionErr.Error()
-		}
-
-		ok := hiveRes.OK && notionRes.OK
-		resp := map[string]any{
-			"ok":        ok,
-			"timestamp": time.Now().Format(time.RFC3339),
-			"services": map[string]serviceHealth{
-				"hive":      hiveRes,
-				"cubecraft": notionRes,
-			},
-		}
-		code := http.StatusOK
-		if !ok {
-			code = http.StatusServiceUnavailable
-		}
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(code)
-		_ = json.NewEncoder(w).Encode(resp)
-	})
-
-	r.Route("/hive", func(r chi.Router) {
-		r.Get("/columns", h.Columns)
-		r.Get("/{column}", h.ByColumn)
-		r.Get("/updates", h.Updates)
-	})
-
-	r.Route("/cubecraft", func(r chi.Router) {
-		r.Get("/columns", cc.Columns)
-		r.Get("/{column}", cc.ByColumn)
-		r.Get("/updates", cc.Updates)
-	})
+		st = stats.NewHandlers(a.History, cfg.History.StatsMaxAge.Duration, cfg.History.TrendingWindow.Duration, statsOpts...)
+		r.With(a.Auth.Require(auth.ScopeRead)).Get("/stats", st.Stats)
+	}
+
+	var snapshots *archive.Handlers
+	var hiveOpts []hive.HandlerOption
+	var ccOpts []cubecraft.HandlerOption
+	if a.Archive != nil {
+		snapshots = archive.NewHandlers(a.Archive)
+		r.With(a.Auth.Require(auth.ScopeRead)).Get("/diff", snapshots.Diff)
+		hiveOpts = append(hiveOpts, hive.WithArchive(a.Archive))
+		ccOpts = append(ccOpts, cubecraft.WithArchive(a.Archive))
+	}
+
+	if a.HiveService != nil {
+		h := hive.NewHandlers(a.HiveService, hiveOpts...)
+		r.Route("/hive", func(r chi.Router) {
+			r.Use(a.Auth.Require(auth.ScopeRead), a.Auth.GuardCacheBypass)
+			r.Get("/columns", h.Columns)
+			r.Get("/languages", h.Languages)
+			r.With(a.RateLimit.Crawl).Get("/{column}", h.ByColumn)
+			r.Get("/updates", h.Updates)
+			if st != nil {
+				r.Get("/eta-report", st.ETAReport("hive"))
+				r.Get("/trending", st.Trending("hive"))
+				r.Get("/items/{id}/upvotes", st.Upvotes("hive"))
+			}
+			if a.Archive != nil {
+				r.Route("/snapshots", snapshots.Routes("hive"))
+			}
+		})
+	}
+
+	for _, nb := range a.NotionBoards {
+		cc := cubecraft.NewHandlers(nb.Service, ccOpts...)
+		r.Route("/"+nb.Name, func(r chi.Router) {
+			r.Use(a.Auth.Require(auth.ScopeRead), a.Auth.GuardCacheBypass)
+			r.Get("/columns", cc.Columns)
+			r.Get("/schema", cc.Schema)
+			r.With(a.RateLimit.Crawl).Get("/{column}", cc.ByColumn)
+			r.Get("/updates", cc.Updates)
+			if a.Archive != nil {
+				r.Route("/snapshots", snapshots.Routes(nb.Name))
+			}
+		})
+	}
 
 	return r
 }
-
-func colorLogger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
-		start := time.Now()
-		next.ServeHTTP(ww, r)
-		duration := time.Since(start)
-
-		status := ww.Status()
-		color := ""
-		reset := "\033[0m"
-		switch {
-		case status >= 200 && status < 300:
-			color = "\033[32m"
-		case status >= 400 && status < 500:
-			color = "\033[33m"
-		case status >= 500:
-			color = "\033[31m"
-		default:
-			color = "\033[37m"
-		}
-
-		log.Printf("%s %s %s%d%s %s",
-			r.Method,
-			r.URL.Path,
-			color, status, reset,
-			duration,
-		)
-	})
-}
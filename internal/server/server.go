@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"roadmapapi/internal/config"
+)
+
+type ShutdownFunc func(ctx context.Context) error
+
+type Server struct {
+	cfg    config.ServerConfig
+	srv    *http.Server
+	certs  *certReloader
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	hooks []namedHook
+}
+
+type namedHook struct {
+	name string
+	fn   ShutdownFunc
+}
+
+func New(cfg config.ServerConfig, h http.Handler, logger *slog.Logger) (*Server, error) {
+	s := &Server{
+		cfg:    cfg,
+		logger: logger,
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           h,
+			ReadTimeout:       cfg.ReadTimeout.Duration,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout.Duration,
+			WriteTimeout:      cfg.WriteTimeout.Duration,
+			IdleTimeout:       cfg.IdleTimeout.Duration,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		},
+	}
+	if cfg.TLS.Enabled() {
+		certs, err := newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		s.srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+	return s, nil
+}
+
+// OnShutdown registers fn to run after the listener has drained. Hooks run
+// in reverse registration order so that later components, which usually
+// depend on earlier ones, are stopped first.
+func (s *Server) OnShutdown(name string, fn ShutdownFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, namedHook{name: name, fn: fn})
+}
+
+// Run serves until ctx is done or serving fails, then drains connections.
+// The shutdown hooks run on every exit path, including a failed listen. Drain
+// and hooks share one ShutdownTimeout deadline, of which the drain may use
+// all but HooksTimeout.
+func (s *Server) Run(ctx context.Context) (err error) {
+	var deadline time.Time
+	defer func() { err = errors.Join(err, s.runHooks(deadline)) }()
+
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if s.certs != nil {
+		go s.certs.watch(watchCtx, s.cfg.TLS.ReloadInterval.Duration)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		s.logger.Info("Roadmap API running", "addr", ln.Addr().String(), "tls", s.certs != nil)
+		if s.certs != nil {
+			serveErr <- s.srv.ServeTLS(ln, "", "")
+		} else {
+			serveErr <- s.srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	deadline = time.Now().Add(s.cfg.ShutdownTimeout.Duration)
+	s.logger.Info("shutting down, draining connections", "deadline", s.cfg.ShutdownTimeout.Duration)
+	drainCtx, cancel := context.WithDeadline(context.Background(), deadline.Add(-s.cfg.HooksTimeout.Duration))
+	defer cancel()
+	if err := s.srv.Shutdown(drainCtx); err != nil {
+		_ = s.srv.Close()
+		return fmt.Errorf("http server: %w", err)
+	}
+	return nil
+}
+
+// runHooks runs the hooks until deadline, or for ShutdownTimeout when
+// serving ended without a drain.
+func (s *Server) runHooks(deadline time.Time) error {
+	if deadline.IsZero() {
+		deadline = time.Now().Add(s.cfg.ShutdownTimeout.Duration)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	s.mu.Lock()
+	hooks := append([]namedHook(nil), s.hooks...)
+	s.mu.Unlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"roadmapapi/internal/config"
+)
+
+func newTestServer(t *testing.T, addr string, h http.Handler) *Server {
+	t.Helper()
+	s, err := New(config.ServerConfig{
+		Addr:            addr,
+		ShutdownTimeout: config.Duration{Duration: 300 * time.Millisecond},
+		HooksTimeout:    config.Duration{Duration: 100 * time.Millisecond},
+	},
+		h, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestHooksRunWhenListenFails(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := newTestServer(t, ln.Addr().String(), http.NotFoundHandler())
+	ran := false
+	s.OnShutdown("app", func(context.Context) error { ran = true; return nil })
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("Run on a taken address returned nil")
+	}
+	if !ran {
+		t.Error("shutdown hook did not run after the listen failed")
+	}
+}
+
+func TestHooksShareTheShutdownDeadline(t *testing.T) {
+	// A handler that outlives the drain deadline uses up the drain's time.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	h := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	s := newTestServer(t, addr, h)
+
+	var order []string
+	var hookErr error
+	var hookDeadline time.Time
+	s.OnShutdown("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		hookErr = ctx.Err()
+		hookDeadline, _ = ctx.Deadline()
+		return nil
+	})
+	s.OnShutdown("second", func(context.Context) error { order = append(order, "second"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	go func() {
+		for range 50 {
+			if resp, err := http.Get("http://" + addr); err == nil {
+				resp.Body.Close()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	<-started
+	stopAt := time.Now()
+	cancel()
+
+	if err := <-done; err == nil {
+		t.Error("Run returned nil although the drain timed out")
+	}
+	if hookErr != nil {
+		t.Errorf("hook context was already done: %v", hookErr)
+	}
+	if limit := stopAt.Add(300 * time.Millisecond); hookDeadline.After(limit.Add(50 * time.Millisecond)) {
+		t.Errorf("hook deadline is %s after the stop, want at most the shutdown timeout", hookDeadline.Sub(stopAt))
+	}
+	if len(order) != 2 || order[0] != "second" {
+		t.Errorf("hooks ran as %v, want [second first]", order)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string, logger *slog.Logger) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	mod := c.latestModTime()
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = mod
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		if st, err := os.Stat(f); err == nil && st.ModTime().After(latest) {
+			latest = st.ModTime()
+		}
+	}
+	return latest
+}
+
+func (c *certReloader) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.latestModTime().After(c.modTime)
+}
+
+// watch reloads the key pair on SIGHUP and, when interval is positive,
+// whenever the files on disk are newer than the loaded pair. A failed
+// reload keeps serving the previous certificate.
+func (c *certReloader) watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			if !c.changed() {
+				continue
+			}
+		}
+		if err := c.reload(); err != nil {
+			c.logger.Error("tls reload failed, keeping previous certificate", "cert_file", c.certFile, "error", err)
+			continue
+		}
+		c.logger.Info("tls certificate reloaded", "cert_file", c.certFile)
+	}
+}
@@ -0,0 +1,212 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"roadmapapi/internal/history"
+)
+
+// InProgressColumn is the column whose overdue items the ETA report lists.
+const InProgressColumn = "in-progress"
+
+const (
+	ETAOnTime  = "on_time"
+	ETALate    = "late"
+	ETARemoved = "eta_removed"
+	ETAPending = "pending"
+	ETAOverdue = "overdue"
+)
+
+type ETAItem struct {
+	ID         string              `json:"id"`
+	Title      string              `json:"title"`
+	Category   string              `json:"category"`
+	Column     string              `json:"column"`
+	ETA        string              `json:"eta,omitempty"`
+	FirstETA   string              `json:"firstEta,omitempty"`
+	ETAHistory []history.ETAChange `json:"etaHistory"`
+	ReleasedAt *time.Time          `json:"releasedAt,omitempty"`
+	Outcome    string              `json:"outcome"`
+	// SlipDays is the release date minus the ETA in effect at release;
+	// negative means early.
+	SlipDays    *int `json:"slipDays,omitempty"`
+	OverdueDays int  `json:"overdueDays,omitempty"`
+}
+
+type ETACategory struct {
+	Items           int     `json:"items"`
+	OnTime          int     `json:"onTime"`
+	Late            int     `json:"late"`
+	ETARemoved      int     `json:"etaRemoved"`
+	Pending         int     `json:"pending"`
+	Overdue         int     `json:"overdue"`
+	AverageSlipDays float64 `json:"averageSlipDays"`
+	MedianSlipDays  float64 `json:"medianSlipDays"`
+}
+
+type ETAReport struct {
+	GeneratedAt  time.Time              `json:"generatedAt"`
+	TrackedSince time.Time              `json:"trackedSince"`
+	Overdue      []ETAItem              `json:"overdue"`
+	Categories   map[string]ETACategory `json:"categories"`
+	Items        []ETAItem              `json:"items"`
+}
+
+// ComputeETA classifies every item that has had an ETA. Released items are
+// judged against the ETA in effect when they were released; items released
+// before tracking started are left out since their release date is unknown.
+func ComputeETA(rec *history.Recorder, source, category string, now time.Time) (ETAReport, bool) {
+	items, start, ok := rec.Snapshot(source)
+	if !ok {
+		return ETAReport{}, false
+	}
+	rep := ETAReport{
+		GeneratedAt:  now.UTC(),
+		TrackedSince: start,
+		Overdue:      []ETAItem{},
+		Categories:   make(map[string]ETACategory),
+		Items:        []ETAItem{},
+	}
+	slips := make(map[string][]int)
+	today := day(now)
+	for i := range items {
+		it := &items[i]
+		if len(it.ETAChanges) == 0 || (category != "" && it.Category != category) {
+			continue
+		}
+		out := ETAItem{
+			ID:         it.ID,
+			Title:      it.Title,
+			Category:   it.Category,
+			Column:     it.Column,
+			ETA:        it.ETA,
+			FirstETA:   firstETA(it.ETAChanges),
+			ETAHistory: it.ETAChanges,
+		}
+		if it.Column == ReleasedColumn {
+			at, ok := it.ReleasedAt(ReleasedColumn, start)
+			if !ok {
+				continue
+			}
+			out.ReleasedAt = &at
+			eta, set := etaAt(it.ETAChanges, at)
+			switch {
+			case !set:
+				continue
+			case eta == "":
+				out.Outcome = ETARemoved
+			default:
+				d, ok := parseETA(eta)
+				if !ok {
+					continue
+				}
+				slip := int(day(at).Sub(d).Hours() / 24)
+				out.SlipDays = &slip
+				out.Outcome = ETAOnTime
+				if slip > 0 {
+					out.Outcome = ETALate
+				}
+				slips[it.Category] = append(slips[it.Category], slip)
+			}
+		} else {
+			d, ok := parseETA(it.ETA)
+			switch {
+			case it.ETA == "":
+				out.Outcome = ETARemoved
+			case !ok:
+				continue
+			case d.Before(today):
+				out.Outcome = ETAOverdue
+				out.OverdueDays = int(today.Sub(d).Hours() / 24)
+			default:
+				out.Outcome = ETAPending
+			}
+		}
+
+		c := rep.Categories[it.Category]
+		c.Items++
+		switch out.Outcome {
+		case ETAOnTime:
+			c.OnTime++
+		case ETALate:
+			c.Late++
+		case ETARemoved:
+			c.ETARemoved++
+		case ETAPending:
+			c.Pending++
+		case ETAOverdue:
+			c.Overdue++
+		}
+		rep.Categories[it.Category] = c
+		rep.Items = append(rep.Items, out)
+		if out.Outcome == ETAOverdue && it.Column == InProgressColumn {
+			rep.Overdue = append(rep.Overdue, out)
+		}
+	}
+	for cat, s := range slips {
+		c := rep.Categories[cat]
+		c.AverageSlipDays, c.MedianSlipDays = slipStats(s)
+		rep.Categories[cat] = c
+	}
+	sort.SliceStable(rep.Overdue, func(i, j int) bool { return rep.Overdue[i].OverdueDays > rep.Overdue[j].OverdueDays })
+	return rep, true
+}
+
+func firstETA(changes []history.ETAChange) string {
+	for _, c := range changes {
+		if c.ETA != "" {
+			return c.ETA
+		}
+	}
+	return ""
+}
+
+// etaAt returns the ETA in effect just before t and whether one had ever
+// been set by then. Hive tends to clear the ETA in the same poll that shows
+// an item released, so a change at t only counts when nothing came before.
+func etaAt(changes []history.ETAChange, t time.Time) (string, bool) {
+	eta, set := "", false
+	for _, c := range changes {
+		if !c.At.Before(t) {
+			if !set && c.At.Equal(t) {
+				eta, set = c.ETA, c.ETA != ""
+			}
+			break
+		}
+		eta = c.ETA
+		set = set || c.ETA != ""
+	}
+	return eta, set
+}
+
+// parseETA returns the UTC day of an ETA, which Hive sends as a timestamp
+// but which only means anything as a date.
+func parseETA(eta string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, eta); err == nil {
+			return day(t), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func slipStats(s []int) (avg, median float64) {
+	sort.Ints(s)
+	sum := 0
+	for _, v := range s {
+		sum += v
+	}
+	avg = round(float64(sum) / float64(len(s)))
+	if n := len(s); n%2 == 1 {
+		median = float64(s[n/2])
+	} else {
+		median = float64(s[n/2-1]+s[n/2]) / 2
+	}
+	return avg, median
+}
@@ -0,0 +1,186 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"roadmapapi/internal/history"
+)
+
+// etaRecorder replays three Hive polls:
+//
+//	late     released after its ETA
+//	ontime   released before its ETA
+//	cleared  released in the same poll that cleared its ETA
+//	pulled   ETA removed a poll before release
+//	overdue  still in progress past its ETA
+//	pending  ETA still ahead
+//	newrel   first seen already released, with an ETA
+//	garbage  unparsable ETA
+//	noeta    never had an ETA
+//	baseline released before tracking started
+func etaRecorder(t *testing.T) *history.Recorder {
+	t.Helper()
+	rec := newRecorder(t, "hive")
+	games := func(id, eta string) history.Observation {
+		return history.Observation{ID: id, Title: id, Category: "Games", ETA: eta}
+	}
+	polls := []struct {
+		at      time.Time
+		columns map[string][]history.Observation
+	}{
+		{time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC), map[string][]history.Observation{
+			"in-progress": {games("late", "2025-03-10T00:00:00Z"), games("ontime", "2025-03-20"), games("cleared", "2025-03-08"), games("pulled", "2025-03-15"), games("overdue", "2025-03-01"), games("garbage", "soon"), games("noeta", "")},
+			"coming-next": {games("pending", "2025-04-01T00:00:00Z")},
+			"released":    {games("baseline", "2025-02-01")},
+		}},
+		{time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC), map[string][]history.Observation{
+			"in-progress": {games("late", "2025-03-10T00:00:00Z"), games("cleared", "2025-03-08"), games("pulled", ""), games("overdue", "2025-03-01"), games("garbage", "soon"), games("noeta", "")},
+			"coming-next": {games("pending", "2025-04-01T00:00:00Z")},
+			"released":    {games("ontime", "2025-03-20"), games("baseline", "2025-02-01"), {ID: "newrel", Category: "Lobby", ETA: "2025-03-09"}},
+		}},
+		{time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC), map[string][]history.Observation{
+			"in-progress": {games("overdue", "2025-03-01"), games("garbage", "soon"), games("noeta", "")},
+			"coming-next": {games("pending", "2025-04-01T00:00:00Z")},
+			"released":    {games("late", "2025-03-10T00:00:00Z"), games("cleared", ""), games("pulled", ""), games("ontime", "2025-03-20"), games("baseline", "2025-02-01"), {ID: "newrel", Category: "Lobby", ETA: "2025-03-09"}},
+		}},
+	}
+	for _, p := range polls {
+		if err := rec.Observe("hive", p.columns, p.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec
+}
+
+func TestComputeETA(t *testing.T) {
+	rec := etaRecorder(t)
+	now := time.Date(2025, 3, 14, 8, 0, 0, 0, time.UTC)
+	rep, ok := ComputeETA(rec, "hive", "", now)
+	if !ok {
+		t.Fatal("ComputeETA found no history")
+	}
+
+	type result struct {
+		outcome string
+		slip    *int
+		overdue int
+	}
+	slip := func(d int) *int { return &d }
+	want := map[string]result{
+		"late":    {ETALate, slip(2), 0},
+		"ontime":  {ETAOnTime, slip(-13), 0},
+		"cleared": {ETALate, slip(4), 0},
+		"pulled":  {ETARemoved, nil, 0},
+		"overdue": {ETAOverdue, nil, 13},
+		"pending": {ETAPending, nil, 0},
+		"newrel":  {ETAOnTime, slip(-2), 0},
+	}
+	got := make(map[string]result, len(rep.Items))
+	for _, it := range rep.Items {
+		got[it.ID] = result{it.Outcome, it.SlipDays, it.OverdueDays}
+	}
+	if !reflect.DeepEqual(got, want) {
+		for id, w := range want {
+			g := got[id]
+			if g.outcome != w.outcome || !reflect.DeepEqual(g.slip, w.slip) || g.overdue != w.overdue {
+				t.Errorf("%s: outcome %q, slip %v, overdue %d; want %q, %v, %d", id, g.outcome, deref(g.slip), g.overdue, w.outcome, deref(w.slip), w.overdue)
+			}
+		}
+		for id := range got {
+			if _, ok := want[id]; !ok {
+				t.Errorf("%s should not be in the report", id)
+			}
+		}
+	}
+
+	wantCats := map[string]ETACategory{
+		"Games": {Items: 6, OnTime: 1, Late: 2, ETARemoved: 1, Pending: 1, Overdue: 1, AverageSlipDays: -2.33, MedianSlipDays: 2},
+		"Lobby": {Items: 1, OnTime: 1, AverageSlipDays: -2, MedianSlipDays: -2},
+	}
+	if !reflect.DeepEqual(rep.Categories, wantCats) {
+		t.Errorf("categories = %+v, want %+v", rep.Categories, wantCats)
+	}
+	if len(rep.Overdue) != 1 || rep.Overdue[0].ID != "overdue" {
+		t.Errorf("overdue = %+v, want only the overdue item", rep.Overdue)
+	}
+
+	lobby, _ := ComputeETA(rec, "hive", "Lobby", now)
+	if len(lobby.Items) != 1 || lobby.Items[0].ID != "newrel" {
+		t.Errorf("category filter kept %+v", lobby.Items)
+	}
+	if _, ok := ComputeETA(rec, "cubecraft", "", now); ok {
+		t.Error("ComputeETA reported an unknown source")
+	}
+}
+
+func deref(p *int) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestETAAt(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) time.Time { return t0.AddDate(0, 0, days) }
+	tests := []struct {
+		name    string
+		changes []history.ETAChange
+		t       time.Time
+		want    string
+		wantSet bool
+	}{
+		{"no changes", nil, at(5), "", false},
+		{"set before", []history.ETAChange{{At: at(0), ETA: "2025-03-10"}}, at(5), "2025-03-10", true},
+		{"latest before wins", []history.ETAChange{{At: at(0), ETA: "2025-03-10"}, {At: at(2), ETA: "2025-03-20"}, {At: at(9), ETA: "2025-04-01"}}, at(5), "2025-03-20", true},
+		{"removed before", []history.ETAChange{{At: at(0), ETA: "2025-03-10"}, {At: at(2)}}, at(5), "", true},
+		{"cleared at release keeps the earlier ETA", []history.ETAChange{{At: at(0), ETA: "2025-03-10"}, {At: at(5)}}, at(5), "2025-03-10", true},
+		{"first set at release", []history.ETAChange{{At: at(5), ETA: "2025-03-04"}}, at(5), "2025-03-04", true},
+		{"only set after", []history.ETAChange{{At: at(6), ETA: "2025-03-10"}}, at(5), "", false},
+	}
+	for _, tt := range tests {
+		eta, set := etaAt(tt.changes, tt.t)
+		if eta != tt.want || set != tt.wantSet {
+			t.Errorf("%s: etaAt() = %q, %v, want %q, %v", tt.name, eta, set, tt.want, tt.wantSet)
+		}
+	}
+}
+
+func TestParseETA(t *testing.T) {
+	tests := []struct {
+		eta  string
+		want string
+		ok   bool
+	}{
+		{"2025-03-10T00:00:00Z", "2025-03-10", true},
+		{"2025-03-10T23:30:00-05:00", "2025-03-11", true},
+		{"2025-03-10", "2025-03-10", true},
+		{"soon", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		d, ok := parseETA(tt.eta)
+		if ok != tt.ok || (ok && d.Format(time.DateOnly) != tt.want) {
+			t.Errorf("parseETA(%q) = %s, %v, want %s, %v", tt.eta, d.Format(time.DateOnly), ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSlipStats(t *testing.T) {
+	tests := []struct {
+		in          []int
+		avg, median float64
+	}{
+		{[]int{3}, 3, 3},
+		{[]int{4, -13, 2}, -2.33, 2},
+		{[]int{1, 2, 3, 10}, 4, 2.5},
+	}
+	for _, tt := range tests {
+		avg, median := slipStats(tt.in)
+		if avg != tt.avg || median != tt.median {
+			t.Errorf("slipStats(%v) = %v, %v, want %v, %v", tt.in, avg, median, tt.avg, tt.median)
+		}
+	}
+}
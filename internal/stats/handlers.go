@@ -0,0 +1,304 @@
+package stats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"roadmapapi/internal/history"
+	"roadmapapi/internal/problem"
+)
+
+const (
+	defaultRange = 90 * 24 * time.Hour
+	maxRange     = 2 * 366 * 24 * time.Hour
+	maxCached    = 64
+	maxWindow    = 90 * 24 * time.Hour
+	maxTrending  = 100
+)
+
+type cached struct {
+	at      time.Time
+	version uint64
+	etag    string
+	body    []byte
+}
+
+type HandlerOption func(*Handlers)
+
+// WithPrivateCache marks reports as private to the caller, for when the
+// route sits behind authentication and shared caches must not keep them.
+func WithPrivateCache() HandlerOption {
+	return func(h *Handlers) { h.private = true }
+}
+
+type Handlers struct {
+	rec     *history.Recorder
+	maxAge  time.Duration
+	window  time.Duration
+	private bool
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+func NewHandlers(rec *history.Recorder, maxAge, trendingWindow time.Duration, opts ...HandlerOption) *Handlers {
+	h := &Handlers{rec: rec, maxAge: maxAge, window: trendingWindow, cache: make(map[string]cached)}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+// Stats serves the analytics report. Reports are cached per query until the
+// history changes or maxAge passes, and carry an ETag so clients can
+// revalidate cheaply.
+func (h *Handlers) Stats(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	q, err := h.parseQuery(r, now)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err)
+		return
+	}
+	v := r.URL.Query()
+	key := cacheKey(q, v.Get("from") != "", v.Get("to") != "")
+	version := h.rec.Version()
+
+	h.mu.Lock()
+	c, ok := h.cache[key]
+	h.mu.Unlock()
+	if !ok || c.version != version || now.Sub(c.at) > h.maxAge {
+		rep := Compute(h.rec, q, now)
+		body, err := json.Marshal(rep)
+		if err != nil {
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, err)
+			return
+		}
+		c = cached{at: now, version: version, etag: etag(rep), body: body}
+		h.mu.Lock()
+		if len(h.cache) >= maxCached {
+			clear(h.cache)
+		}
+		h.cache[key] = c
+		h.mu.Unlock()
+	}
+
+	scope := "public"
+	if h.private {
+		scope = "private"
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(h.maxAge.Seconds())))
+	w.Header().Set("ETag", c.etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && match == c.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(c.body, '\n'))
+}
+
+// etag hashes the report without its timestamps, so that a recomputed report
+// with the same figures keeps its ETag.
+func etag(rep Report) string {
+	rep.From, rep.To, rep.GeneratedAt = time.Time{}, time.Time{}, time.Time{}
+	b, _ := json.Marshal(rep)
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ETAReport serves the ETA accuracy report for one source, optionally
+// limited to a category.
+func (h *Handlers) ETAReport(source string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := ComputeETA(h.rec, source, r.URL.Query().Get("category"), time.Now())
+		if !ok {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, fmt.Errorf("no history for source %q", source))
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+	}
+}
+
+// Trending serves a source's items ranked by upvote velocity. ?window=
+// accepts a Go duration or a number of days such as 7d.
+func (h *Handlers) Trending(source string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := h.window
+		if raw := r.URL.Query().Get("window"); raw != "" {
+			d, err := parseWindow(raw)
+			if err != nil {
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, err)
+				return
+			}
+			window = d
+		}
+		limit, err := strconv.Atoi(strFromQuery(r, "limit", "20"))
+		if err != nil || limit < 1 || limit > maxTrending {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("limit must be between 1 and %d", maxTrending))
+			return
+		}
+		now := time.Now().UTC()
+		items, ok := Trending(h.rec, source, strings.ToLower(r.URL.Query().Get("column")), window, limit, now)
+		if !ok {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, fmt.Errorf("no history for source %q", source))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"window": window.String(),
+			"from":   now.Add(-window),
+			"to":     now,
+			"items":  items,
+		})
+	}
+}
+
+// Upvotes serves one item's upvote history, optionally limited by ?from=
+// and ?to=.
+func (h *Handlers) Upvotes(source string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		it, ok := h.rec.Lookup(source, id)
+		if !ok {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, fmt.Errorf("no history for item %q", id))
+			return
+		}
+		from, to := it.FirstSeen, time.Now().UTC()
+		var err error
+		if raw := r.URL.Query().Get("from"); raw != "" {
+			if from, err = parseTime(raw, false); err != nil {
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("from: %w", err))
+				return
+			}
+		}
+		if raw := r.URL.Query().Get("to"); raw != "" {
+			if to, err = parseTime(raw, true); err != nil {
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidParameter, fmt.Errorf("to: %w", err))
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":        it.ID,
+			"title":     it.Title,
+			"column":    it.Column,
+			"upvotes":   it.Upvotes,
+			"firstSeen": it.FirstSeen,
+			"lastSeen":  it.LastSeen,
+			"samples":   UpvoteSeries(it, from, to),
+		})
+	}
+}
+
+func parseWindow(raw string) (time.Duration, error) {
+	var d time.Duration
+	if n, err := strconv.Atoi(strings.TrimSuffix(raw, "d")); err == nil && strings.HasSuffix(raw, "d") {
+		d = time.Duration(n) * 24 * time.Hour
+	} else if d, err = time.ParseDuration(raw); err != nil {
+		return 0, fmt.Errorf("window must be a duration such as 48h or 7d, got %q", raw)
+	}
+	if d <= 0 || d > maxWindow {
+		return 0, errors.New("window must be positive and at most 90d")
+	}
+	return d, nil
+}
+
+// cacheKey identifies a query. Open-ended ranges ending "now" are keyed on
+// the given from, or on the default range when from was omitted too, so that
+// repeated requests share an entry rather than one per request time.
+func cacheKey(q Query, explicitFrom, explicitTo bool) string {
+	from, to := "default", "now"
+	if explicitTo {
+		to = q.To.Format(time.RFC3339Nano)
+	}
+	if explicitFrom || explicitTo {
+		from = q.From.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s", strings.Join(q.Sources, ","),
+		from, to, q.GroupBy, q.Dimension)
+}
+
+func (h *Handlers) parseQuery(r *http.Request, now time.Time) (Query, error) {
+	v := r.URL.Query()
+	q := Query{
+		GroupBy:   strings.ToLower(strFromQuery(r, "groupBy", "week")),
+		Dimension: strings.ToLower(strFromQuery(r, "dimension", "category")),
+		To:        now,
+	}
+	if q.GroupBy != "week" && q.GroupBy != "month" {
+		return q, fmt.Errorf("groupBy must be week or month, got %q", q.GroupBy)
+	}
+	if q.Dimension != "category" && q.Dimension != "network" {
+		return q, fmt.Errorf("dimension must be category or network, got %q", q.Dimension)
+	}
+
+	known := h.rec.Sources()
+	switch src := strings.ToLower(v.Get("source")); src {
+	case "", "all":
+		q.Sources = known
+	default:
+		if !slices.Contains(known, src) {
+			return q, fmt.Errorf("unknown source %q (available: %s)", src, strings.Join(known, ", "))
+		}
+		q.Sources = []string{src}
+	}
+
+	var err error
+	if raw := v.Get("to"); raw != "" {
+		if q.To, err = parseTime(raw, true); err != nil {
+			return q, fmt.Errorf("to: %w", err)
+		}
+	}
+	q.From = q.To.Add(-defaultRange)
+	if raw := v.Get("from"); raw != "" {
+		if q.From, err = parseTime(raw, false); err != nil {
+			return q, fmt.Errorf("from: %w", err)
+		}
+	}
+	switch {
+	case !q.From.Before(q.To):
+		return q, errors.New("from must be before to")
+	case q.To.Sub(q.From) > maxRange:
+		return q, errors.New("range must not exceed two years")
+	}
+	return q, nil
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates. A plain date used as
+// the end of a range covers that whole day.
+func parseTime(raw string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", raw)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func strFromQuery(r *http.Request, key, def string) string {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
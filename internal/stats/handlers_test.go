@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatsCacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []HandlerOption
+		want string
+	}{
+		{"public", nil, "public, max-age=60"},
+		{"behind auth", []HandlerOption{WithPrivateCache()}, "private, max-age=60"},
+	}
+	for _, tt := range tests {
+		h := NewHandlers(newRecorder(t, "hive"), time.Minute, 7*24*time.Hour, tt.opts...)
+		rec := httptest.NewRecorder()
+		h.Stats(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tt.name, rec.Code)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: Cache-Control = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	h := NewHandlers(newRecorder(t, "hive"), time.Minute, 7*24*time.Hour)
+	key := func(url string, now time.Time) string {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		q, err := h.parseQuery(r, now)
+		if err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		v := r.URL.Query()
+		return cacheKey(q, v.Get("from") != "", v.Get("to") != "")
+	}
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	later := now.Add(90 * time.Second)
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"default range", "/stats", "/stats", true},
+		{"open-ended from", "/stats?from=2025-03-01", "/stats?from=2025-03-01", true},
+		{"different from", "/stats?from=2025-03-01", "/stats?from=2025-03-02", false},
+		{"explicit to", "/stats?to=2025-03-10", "/stats?to=2025-03-10", true},
+		{"open-ended against explicit to", "/stats?from=2025-03-01", "/stats?from=2025-03-01&to=2025-03-20", false},
+	}
+	for _, tt := range tests {
+		if got := key(tt.a, now) == key(tt.b, later); got != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"roadmapapi/internal/history"
+)
+
+// ReleasedColumn is the column both sources use for shipped items.
+const ReleasedColumn = "released"
+
+type Query struct {
+	Sources   []string
+	From      time.Time
+	To        time.Time
+	GroupBy   string // week or month
+	Dimension string // category or network
+}
+
+type Report struct {
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	GroupBy     string                 `json:"groupBy"`
+	Dimension   string                 `json:"dimension"`
+	GeneratedAt time.Time              `json:"generatedAt"`
+	Sources     map[string]SourceStats `json:"sources"`
+}
+
+type SourceStats struct {
+	TrackedSince time.Time `json:"trackedSince"`
+	Items        int       `json:"items"`
+	// TimeInColumn covers completed stays that ended inside the range. Open
+	// counts items currently in the column.
+	TimeInColumn map[string]Durations `json:"timeInColumn"`
+	Releases     []PeriodCount        `json:"releases"`
+	// LeadTime runs from first seen to released, for items first seen after
+	// tracking started.
+	LeadTime  Durations         `json:"leadTime"`
+	Breakdown []PeriodBreakdown `json:"breakdown"`
+}
+
+type Durations struct {
+	Samples      int     `json:"samples"`
+	Open         int     `json:"open,omitempty"`
+	MedianHours  float64 `json:"medianHours"`
+	P90Hours     float64 `json:"p90Hours"`
+	AverageHours float64 `json:"averageHours"`
+}
+
+type PeriodCount struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	Count  int       `json:"count"`
+}
+
+type PeriodBreakdown struct {
+	Period string                 `json:"period"`
+	Start  time.Time              `json:"start"`
+	Counts map[string]*Dimensions `json:"counts"`
+}
+
+type Dimensions struct {
+	New      int `json:"new"`
+	Released int `json:"released"`
+}
+
+func Compute(rec *history.Recorder, q Query, now time.Time) Report {
+	rep := Report{
+		From:        q.From,
+		To:          q.To,
+		GroupBy:     q.GroupBy,
+		Dimension:   q.Dimension,
+		GeneratedAt: now.UTC(),
+		Sources:     make(map[string]SourceStats, len(q.Sources)),
+	}
+	for _, src := range q.Sources {
+		items, start, ok := rec.Snapshot(src)
+		if !ok {
+			continue
+		}
+		rep.Sources[src] = computeSource(items, start, q)
+	}
+	return rep
+}
+
+func computeSource(items []history.Item, start time.Time, q Query) SourceStats {
+	inRange := func(t time.Time) bool { return !t.Before(q.From) && !t.After(q.To) }
+	periods := buckets(q.From, q.To, q.GroupBy)
+	index := make(map[string]int, len(periods))
+	releases := make([]PeriodCount, len(periods))
+	breakdown := make([]PeriodBreakdown, len(periods))
+	for i, p := range periods {
+		index[p.label] = i
+		releases[i] = PeriodCount{Period: p.label, Start: p.start}
+		breakdown[i] = PeriodBreakdown{Period: p.label, Start: p.start, Counts: make(map[string]*Dimensions)}
+	}
+	dim := func(it *history.Item, period int) *Dimensions {
+		key := it.Category
+		if q.Dimension == "network" {
+			key = it.Network
+		}
+		if key == "" {
+			key = "unspecified"
+		}
+		d := breakdown[period].Counts[key]
+		if d == nil {
+			d = &Dimensions{}
+			breakdown[period].Counts[key] = d
+		}
+		return d
+	}
+
+	stays := make(map[string][]time.Duration)
+	open := make(map[string]int)
+	var lead []time.Duration
+	for i := range items {
+		it := &items[i]
+		baseline := it.Baseline(start)
+		if !baseline && inRange(it.FirstSeen) {
+			dim(it, index[periodOf(it.FirstSeen, q.GroupBy).label]).New++
+		}
+		tr := it.Transitions
+		for j, t := range tr {
+			if t.To != ReleasedColumn {
+				if j == len(tr)-1 {
+					if t.At.Before(q.To) {
+						open[t.To]++
+					}
+				} else if (j > 0 || !baseline) && inRange(tr[j+1].At) {
+					stays[t.To] = append(stays[t.To], tr[j+1].At.Sub(t.At))
+				}
+				continue
+			}
+			// A move into released, or a new item that showed up already
+			// released, counts as a release. Baseline items do not.
+			if (j == 0 && baseline) || !inRange(t.At) {
+				continue
+			}
+			p := index[periodOf(t.At, q.GroupBy).label]
+			releases[p].Count++
+			dim(it, p).Released++
+			if j > 0 && !baseline {
+				lead = append(lead, t.At.Sub(it.FirstSeen))
+			}
+		}
+	}
+
+	out := SourceStats{
+		TrackedSince: start,
+		Items:        len(items),
+		TimeInColumn: make(map[string]Durations),
+		Releases:     releases,
+		LeadTime:     summarize(lead),
+		Breakdown:    breakdown,
+	}
+	for col, ds := range stays {
+		out.TimeInColumn[col] = summarize(ds)
+	}
+	for col, n := range open {
+		d := out.TimeInColumn[col]
+		d.Open = n
+		out.TimeInColumn[col] = d
+	}
+	return out
+}
+
+func summarize(ds []time.Duration) Durations {
+	if len(ds) == 0 {
+		return Durations{}
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	return Durations{
+		Samples:      len(ds),
+		MedianHours:  round(percentile(ds, 0.5).Hours()),
+		P90Hours:     round(percentile(ds, 0.9).Hours()),
+		AverageHours: round((sum / time.Duration(len(ds))).Hours()),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of sorted ds.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	pos := p * float64(len(ds)-1)
+	lo := int(pos)
+	if lo+1 >= len(ds) {
+		return ds[lo]
+	}
+	frac := pos - float64(lo)
+	return ds[lo] + time.Duration(frac*float64(ds[lo+1]-ds[lo]))
+}
+
+func round(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+type period struct {
+	label string
+	start time.Time
+}
+
+// periodOf returns the ISO week (Monday start) or calendar month containing
+// t, in UTC.
+func periodOf(t time.Time, groupBy string) period {
+	t = t.UTC()
+	if groupBy == "month" {
+		s := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return period{label: s.Format("2006-01"), start: s}
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	s := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	y, w := s.ISOWeek()
+	return period{label: fmt.Sprintf("%d-W%02d", y, w), start: s}
+}
+
+func buckets(from, to time.Time, groupBy string) []period {
+	var out []period
+	for p := periodOf(from, groupBy); !p.start.After(to); {
+		out = append(out, p)
+		if groupBy == "month" {
+			p = periodOf(p.start.AddDate(0, 1, 0), groupBy)
+		} else {
+			p = periodOf(p.start.AddDate(0, 0, 7), groupBy)
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"roadmapapi/internal/history"
+	"roadmapapi/internal/store"
+)
+
+func newRecorder(t *testing.T, source string) *history.Recorder {
+	t.Helper()
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := history.NewRecorder(st, 0, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestComputeSource(t *testing.T) {
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC) // a Monday, 2025-W10
+	day := func(n int) time.Time { return start.AddDate(0, 0, n) }
+	move := func(at time.Time, to string) history.Transition { return history.Transition{At: at, To: to} }
+	items := []history.Item{
+		// Already released in the first poll: says nothing about when.
+		{ID: "baseline-released", Category: "Games", FirstSeen: start, Transitions: []history.Transition{move(start, "released")}},
+		// Baseline item released during tracking: a release, but no stay
+		// or lead time since its first column had no known start.
+		{ID: "baseline-moved", Category: "Lobby", FirstSeen: start, Transitions: []history.Transition{move(start, "in-progress"), move(day(3), "released")}},
+		{ID: "new-flow", Category: "Games", FirstSeen: day(1), Transitions: []history.Transition{move(day(1), "coming-next"), move(day(2), "in-progress"), move(day(9), "released")}},
+		// New item that showed up already released.
+		{ID: "new-released", FirstSeen: day(8), Transitions: []history.Transition{move(day(8), "released")}},
+		{ID: "open", Category: "Games", FirstSeen: start, Transitions: []history.Transition{move(start, "in-progress")}},
+		{ID: "after-range", Category: "Games", FirstSeen: day(40), Transitions: []history.Transition{move(day(40), "coming-next")}},
+	}
+	q := Query{From: start, To: day(28), GroupBy: "week", Dimension: "category"}
+	got := computeSource(items, start, q)
+
+	if got.Items != len(items) || !got.TrackedSince.Equal(start) {
+		t.Errorf("items = %d, trackedSince = %s", got.Items, got.TrackedSince)
+	}
+	wantReleases := map[string]int{"2025-W10": 1, "2025-W11": 2, "2025-W12": 0, "2025-W13": 0, "2025-W14": 0}
+	if len(got.Releases) != len(wantReleases) {
+		t.Errorf("releases has %d periods, want %d", len(got.Releases), len(wantReleases))
+	}
+	for _, r := range got.Releases {
+		if r.Count != wantReleases[r.Period] {
+			t.Errorf("releases in %s = %d, want %d", r.Period, r.Count, wantReleases[r.Period])
+		}
+	}
+	wantTime := map[string]Durations{
+		"coming-next": {Samples: 1, MedianHours: 24, P90Hours: 24, AverageHours: 24},
+		"in-progress": {Samples: 1, Open: 1, MedianHours: 168, P90Hours: 168, AverageHours: 168},
+	}
+	if !reflect.DeepEqual(got.TimeInColumn, wantTime) {
+		t.Errorf("timeInColumn = %+v, want %+v", got.TimeInColumn, wantTime)
+	}
+	if want := (Durations{Samples: 1, MedianHours: 192, P90Hours: 192, AverageHours: 192}); got.LeadTime != want {
+		t.Errorf("leadTime = %+v, want %+v", got.LeadTime, want)
+	}
+	wantBreakdown := map[string]map[string]Dimensions{
+		"2025-W10": {"Lobby": {Released: 1}, "Games": {New: 1}},
+		"2025-W11": {"Games": {Released: 1}, "unspecified": {New: 1, Released: 1}},
+	}
+	for _, b := range got.Breakdown {
+		counts := make(map[string]Dimensions, len(b.Counts))
+		for k, d := range b.Counts {
+			counts[k] = *d
+		}
+		if want := wantBreakdown[b.Period]; len(counts)+len(want) > 0 && !reflect.DeepEqual(counts, want) {
+			t.Errorf("breakdown for %s = %+v, want %+v", b.Period, counts, want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	h := func(hours ...float64) []time.Duration {
+		out := make([]time.Duration, len(hours))
+		for i, v := range hours {
+			out[i] = time.Duration(v * float64(time.Hour))
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		in   []time.Duration
+		want Durations
+	}{
+		{"empty", nil, Durations{}},
+		{"single", h(5), Durations{Samples: 1, MedianHours: 5, P90Hours: 5, AverageHours: 5}},
+		{"even count interpolates", h(4, 1, 3, 2), Durations{Samples: 4, MedianHours: 2.5, P90Hours: 3.7, AverageHours: 2.5}},
+		{"skewed", h(1, 1, 1, 1, 1, 1, 1, 1, 1, 100), Durations{Samples: 10, MedianHours: 1, P90Hours: 10.9, AverageHours: 10.9}},
+		{"rounds to hundredths", h(1.0 / 3), Durations{Samples: 1, MedianHours: 0.33, P90Hours: 0.33, AverageHours: 0.33}},
+	}
+	for _, tt := range tests {
+		if got := summarize(tt.in); got != tt.want {
+			t.Errorf("%s: summarize() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPeriodOf(t *testing.T) {
+	tests := []struct {
+		at        string
+		groupBy   string
+		wantLabel string
+		wantStart string
+	}{
+		{"2025-03-05T10:00:00Z", "week", "2025-W10", "2025-03-03"},
+		{"2025-03-09T23:59:59Z", "week", "2025-W10", "2025-03-03"},
+		{"2024-12-30T00:00:00Z", "week", "2025-W01", "2024-12-30"},
+		{"2027-01-01T00:00:00Z", "week", "2026-W53", "2026-12-28"},
+		// Periods are UTC: this is already Monday in UTC.
+		{"2025-03-09T22:00:00-05:00", "week", "2025-W11", "2025-03-10"},
+		{"2025-02-28T23:00:00-05:00", "month", "2025-03", "2025-03-01"},
+		{"2025-12-31T23:59:59Z", "month", "2025-12", "2025-12-01"},
+	}
+	for _, tt := range tests {
+		at, err := time.Parse(time.RFC3339, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := periodOf(at, tt.groupBy)
+		if p.label != tt.wantLabel || p.start.Format(time.DateOnly) != tt.wantStart {
+			t.Errorf("periodOf(%s, %s) = %s from %s, want %s from %s", tt.at, tt.groupBy, p.label, p.start.Format(time.DateOnly), tt.wantLabel, tt.wantStart)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	rec := newRecorder(t, "hive")
+	t0 := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	polls := []map[string][]history.Observation{
+		{"in-progress": {{ID: "a", Category: "Games"}}, "coming-next": {{ID: "b", Category: "Games"}}},
+		{"in-progress": {{ID: "a", Category: "Games"}}, "released": {{ID: "b", Category: "Games"}}},
+	}
+	for i, p := range polls {
+		if err := rec.Observe("hive", p, t0.AddDate(0, 0, 7*i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rep := Compute(rec, Query{Sources: []string{"hive", "cubecraft"}, From: t0, To: t0.AddDate(0, 1, 0), GroupBy: "month"}, t0.AddDate(0, 1, 0))
+	if _, ok := rep.Sources["cubecraft"]; ok {
+		t.Error("report includes a source without history")
+	}
+	s, ok := rep.Sources["hive"]
+	if !ok {
+		t.Fatal("report is missing hive")
+	}
+	if len(s.Releases) != 2 || s.Releases[0].Period != "2025-03" || s.Releases[0].Count != 1 {
+		t.Errorf("releases = %+v, want one in 2025-03", s.Releases)
+	}
+	if d := s.TimeInColumn["in-progress"]; d.Open != 1 {
+		t.Errorf("in-progress = %+v, want one open item", d)
+	}
+}
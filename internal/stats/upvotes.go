@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"roadmapapi/internal/history"
+)
+
+type TrendingItem struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Column   string `json:"column"`
+	Category string `json:"category"`
+	Upvotes  int    `json:"upvotes"`
+	Gained   int    `json:"gained"`
+	// Since is the start of the measured span: the window start, or when
+	// the item was first seen if that is later.
+	Since        time.Time `json:"since"`
+	VotesPerDay  float64   `json:"votesPerDay"`
+	GrowthPerDay float64   `json:"growthPercentPerDay"`
+}
+
+// Trending ranks items by upvotes gained per day over window. Items first
+// seen inside the window are measured from their first sighting.
+func Trending(rec *history.Recorder, source, column string, window time.Duration, limit int, now time.Time) ([]TrendingItem, bool) {
+	items, _, ok := rec.Snapshot(source)
+	if !ok {
+		return nil, false
+	}
+	start := now.Add(-window)
+	out := make([]TrendingItem, 0, len(items))
+	for i := range items {
+		it := &items[i]
+		if column != "" && it.Column != column {
+			continue
+		}
+		base, since := it.UpvotesAt(start)
+		if since.Before(start) {
+			since = start
+		}
+		gained := it.Upvotes - base
+		if gained <= 0 {
+			continue
+		}
+		// Never divide by less than an hour, so an item seen twice a
+		// minute apart does not top the list.
+		days := max(now.Sub(since), time.Hour).Hours() / 24
+		t := TrendingItem{
+			ID:          it.ID,
+			Title:       it.Title,
+			Column:      it.Column,
+			Category:    it.Category,
+			Upvotes:     it.Upvotes,
+			Gained:      gained,
+			Since:       since,
+			VotesPerDay: round(float64(gained) / days),
+		}
+		if base > 0 {
+			t.GrowthPerDay = round(float64(gained) / float64(base) * 100 / days)
+		}
+		out = append(out, t)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].VotesPerDay != out[j].VotesPerDay {
+			return out[i].VotesPerDay > out[j].VotesPerDay
+		}
+		return out[i].Upvotes > out[j].Upvotes
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, true
+}
+
+// UpvoteSeries returns the samples of it between from and to, led by the
+// count in effect at from so the series starts at the right level.
+func UpvoteSeries(it history.Item, from, to time.Time) []history.UpvoteSample {
+	out := []history.UpvoteSample{}
+	for i, s := range it.UpvoteLog {
+		if s.At.After(to) {
+			break
+		}
+		if s.At.Before(from) {
+			if i+1 < len(it.UpvoteLog) && !it.UpvoteLog[i+1].At.After(from) {
+				continue
+			}
+			s.At = from
+		}
+		out = append(out, s)
+	}
+	return out
+}
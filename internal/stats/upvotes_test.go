@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"roadmapapi/internal/history"
+)
+
+func TestTrending(t *testing.T) {
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	type poll struct {
+		ago time.Duration
+		// votes holds upvotes by column and item ID.
+		votes map[string]map[string]int
+	}
+	day := 24 * time.Hour
+	board := []poll{
+		{10 * day, map[string]map[string]int{"in-progress": {"a": 10, "d": 3}, "coming-next": {"b": 100}}},
+		{5 * day, map[string]map[string]int{"in-progress": {"a": 20, "c": 5, "d": 3}, "coming-next": {"b": 100}}},
+		{day, map[string]map[string]int{"in-progress": {"a": 40, "c": 25, "d": 3}, "coming-next": {"b": 110}}},
+	}
+	type want struct {
+		id          string
+		gained      int
+		votesPerDay float64
+		growth      float64
+	}
+	tests := []struct {
+		name   string
+		polls  []poll
+		column string
+		window time.Duration
+		limit  int
+		want   []want
+	}{
+		{
+			name:   "ranks by votes per day",
+			polls:  board,
+			window: 7 * day,
+			// c was first seen inside the window, so it is measured over
+			// five days from its first count.
+			want: []want{{"a", 30, 4.29, 42.86}, {"c", 20, 4, 80}, {"b", 10, 1.43, 1.43}},
+		},
+		{
+			name:   "ties go to the most upvoted",
+			polls:  board,
+			window: 2 * day,
+			want:   []want{{"a", 20, 10, 50}, {"c", 20, 10, 200}, {"b", 10, 5, 5}},
+		},
+		{
+			name:   "column filter",
+			polls:  board,
+			column: "coming-next",
+			window: 7 * day,
+			want:   []want{{"b", 10, 1.43, 1.43}},
+		},
+		{
+			name:   "limit",
+			polls:  board,
+			window: 7 * day,
+			limit:  1,
+			want:   []want{{"a", 30, 4.29, 42.86}},
+		},
+		{
+			name: "spans shorter than an hour count as one",
+			polls: []poll{
+				{20 * time.Minute, map[string]map[string]int{"in-progress": {"e": 1}}},
+				{0, map[string]map[string]int{"in-progress": {"e": 5}}},
+			},
+			window: 7 * day,
+			want:   []want{{"e", 4, 96, 9600}},
+		},
+		{
+			name: "no gain is left out",
+			polls: []poll{
+				{2 * day, map[string]map[string]int{"in-progress": {"d": 3, "f": 9}}},
+				{day, map[string]map[string]int{"in-progress": {"d": 3, "f": 8}}},
+			},
+			window: 7 * day,
+			want:   []want{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := newRecorder(t, "hive")
+			for _, p := range tt.polls {
+				cols := make(map[string][]history.Observation, len(p.votes))
+				for col, votes := range p.votes {
+					for id, n := range votes {
+						cols[col] = append(cols[col], history.Observation{ID: id, Title: id, Upvotes: n})
+					}
+				}
+				if err := rec.Observe("hive", cols, now.Add(-p.ago)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			items, ok := Trending(rec, "hive", tt.column, tt.window, tt.limit, now)
+			if !ok {
+				t.Fatal("Trending found no history")
+			}
+			got := make([]want, len(items))
+			for i, it := range items {
+				got[i] = want{it.ID, it.Gained, it.VotesPerDay, it.GrowthPerDay}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Trending() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpvoteSeries(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) time.Time { return t0.AddDate(0, 0, days) }
+	it := history.Item{UpvoteLog: []history.UpvoteSample{{At: at(0), Count: 1}, {At: at(2), Count: 3}, {At: at(4), Count: 6}, {At: at(6), Count: 10}}}
+	tests := []struct {
+		name     string
+		it       history.Item
+		from, to time.Time
+		want     []history.UpvoteSample
+	}{
+		{"whole log", it, at(-1), at(7), it.UpvoteLog},
+		{"starts at the count in effect", it, at(3), at(7), []history.UpvoteSample{{At: at(3), Count: 3}, {At: at(4), Count: 6}, {At: at(6), Count: 10}}},
+		{"from on a sample", it, at(2), at(7), []history.UpvoteSample{{At: at(2), Count: 3}, {At: at(4), Count: 6}, {At: at(6), Count: 10}}},
+		{"ends at to", it, at(1), at(4), []history.UpvoteSample{{At: at(1), Count: 1}, {At: at(2), Count: 3}, {At: at(4), Count: 6}}},
+		{"after the last sample", it, at(8), at(9), []history.UpvoteSample{{At: at(8), Count: 10}}},
+		{"empty log", history.Item{}, at(0), at(9), []history.UpvoteSample{}},
+	}
+	for _, tt := range tests {
+		if got := UpvoteSeries(tt.it, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: UpvoteSeries() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
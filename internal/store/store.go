@@ -0,0 +1,92 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Store persists named JSON documents as individual files in a directory.
+// Writes go through a temp file and rename so a crash never leaves a
+// half-written document behind.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) Dir() string { return s.dir }
+
+func (s *Store) path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("store: invalid document name %q", name)
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+// Load decodes the named document into v. It reports false without an error
+// when the document does not exist yet.
+func (s *Store) Load(name string, v any) (bool, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("store: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("store: decode %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (s *Store) Save(name string, v any) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("store: encode %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("store: write %s: %w", name, err)
+	}
+	return nil
+}
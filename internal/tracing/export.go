@@ -0,0 +1,321 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+const (
+	maxQueueSize  = 4096
+	maxBatchSize  = 256
+	flushInterval = 2 * time.Second
+)
+
+type batchProcessor struct {
+	exp Exporter
+
+	mu      sync.Mutex
+	queue   []SpanData
+	stopped bool
+	dropped int
+
+	exportMu sync.Mutex
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newBatchProcessor(exp Exporter) *batchProcessor {
+	p := &batchProcessor{
+		exp:  exp,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go p.loop()
+	return p
+}
+
+func (p *batchProcessor) onEnd(d SpanData) {
+	p.mu.Lock()
+	if p.stopped || len(p.queue) >= maxQueueSize {
+		p.dropped++
+		p.mu.Unlock()
+		return
+	}
+	p.queue = append(p.queue, d)
+	full := len(p.queue) >= maxBatchSize
+	p.mu.Unlock()
+	if full {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (p *batchProcessor) loop() {
+	defer close(p.done)
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-p.wake:
+		case <-p.stop:
+			_ = p.flush(context.Background())
+			return
+		}
+		_ = p.flush(context.Background())
+	}
+}
+
+func (p *batchProcessor) flush(ctx context.Context) error {
+	p.exportMu.Lock()
+	defer p.exportMu.Unlock()
+	for {
+		p.mu.Lock()
+		n := len(p.queue)
+		if n > maxBatchSize {
+			n = maxBatchSize
+		}
+		batch := p.queue[:n:n]
+		p.queue = p.queue[n:]
+		p.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := p.exp.Export(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopped = true
+	p.mu.Unlock()
+	close(p.stop)
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.exp.Shutdown(ctx)
+}
+
+// ForceFlush exports every queued span before returning.
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	return t.processor.flush(ctx)
+}
+
+// MemoryExporter keeps finished spans in memory, for tests and debugging.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewMemoryExporter() *MemoryExporter { return &MemoryExporter{} }
+
+func (m *MemoryExporter) Export(_ context.Context, spans []SpanData) error {
+	m.mu.Lock()
+	m.spans = append(m.spans, spans...)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryExporter) Shutdown(context.Context) error { return nil }
+
+func (m *MemoryExporter) Spans() []SpanData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SpanData(nil), m.spans...)
+}
+
+func (m *MemoryExporter) Reset() {
+	m.mu.Lock()
+	m.spans = nil
+	m.mu.Unlock()
+}
+
+// StdoutExporter writes one OTLP-JSON encoded span per line.
+type StdoutExporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	service string
+}
+
+func NewStdoutExporter(w io.Writer, service string) *StdoutExporter {
+	return &StdoutExporter{w: w, service: service}
+}
+
+func (e *StdoutExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		out := otlpSpanOf(s)
+		if err := enc.Encode(struct {
+			Service string `json:"service"`
+			otlpSpan
+		}{e.service, out}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *StdoutExporter) Shutdown(context.Context) error { return nil }
+
+// OTLPExporter posts spans to an OTLP/HTTP collector using the JSON
+// encoding, e.g. http://localhost:4318/v1/traces.
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+}
+
+func NewOTLPExporter(endpoint, service string, headers map[string]string, timeout time.Duration) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		service:  service,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		out = append(out, otlpSpanOf(s))
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttrs([]Attr{String("service.name", e.service)}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "roadmapapi"},
+				"spans": out,
+			}},
+		}},
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp export: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+type otlpSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              SpanKind    `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr  `json:"attributes,omitempty"`
+	Events            []otlpEvent `json:"events,omitempty"`
+	Status            otlpStatus  `json:"status"`
+}
+
+type otlpAttr struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []otlpAttr `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+func otlpSpanOf(s SpanData) otlpSpan {
+	out := otlpSpan{
+		TraceID:           s.Context.TraceID.String(),
+		SpanID:            s.Context.SpanID.String(),
+		Name:              s.Name,
+		Kind:              s.Kind,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		Attributes:        otlpAttrs(s.Attrs),
+		Status:            otlpStatus{Code: s.Status, Message: s.StatusMessage},
+	}
+	if s.Parent.IsValid() {
+		out.ParentSpanID = s.Parent.String()
+	}
+	for _, ev := range s.Events {
+		out.Events = append(out.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(ev.Time.UnixNano(), 10),
+			Name:         ev.Name,
+			Attributes:   otlpAttrs(ev.Attrs),
+		})
+	}
+	return out
+}
+
+func otlpAttrs(attrs []Attr) []otlpAttr {
+	out := make([]otlpAttr, 0, len(attrs))
+	for _, a := range attrs {
+		var v map[string]any
+		switch x := a.Value.(type) {
+		case string:
+			v = map[string]any{"stringValue": x}
+		case bool:
+			v = map[string]any{"boolValue": x}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": x}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(x)}
+		}
+		out = append(out, otlpAttr{Key: a.Key, Value: v})
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware starts a server span for every inbound request, continuing the
+// caller's trace when a valid traceparent header is present. The span is
+// renamed to the matched route pattern once routing has happened.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc := Extract(r.Header); sc.IsValid() {
+			ctx = ContextWithRemoteSpanContext(ctx, sc)
+		}
+		ctx, span := StartKind(ctx, r.Method+" "+r.URL.Path, KindServer,
+			String("http.request.method", r.Method),
+			String("url.path", r.URL.Path),
+			String("url.query", scrubQuery(r.URL.RawQuery)),
+			String("client.address", r.RemoteAddr),
+			String("http.request_id", middleware.GetReqID(ctx)),
+		)
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer span.End()
+
+		w.Header().Set(traceparentHeader, formatTraceparent(span.SpanContext()))
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if rc := chi.RouteContext(ctx); rc != nil && rc.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rc.RoutePattern())
+			span.SetAttrs(String("http.route", rc.RoutePattern()))
+		}
+		span.SetAttrs(Int("http.response.status_code", status), Int("http.response.body.size", ww.BytesWritten()))
+		if status >= 500 {
+			span.SetStatus(StatusError, http.StatusText(status))
+		}
+	})
+}
+
+// credentialParams are query parameters that can carry secrets, such as the
+// api_key auth accepts. They are left out of url.query since spans are
+// exported.
+var credentialParams = []string{"api_key", "access_token"}
+
+func scrubQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	// Pairs that do not parse are dropped along with the credentials.
+	q, _ := url.ParseQuery(raw)
+	for _, p := range credentialParams {
+		q.Del(p)
+	}
+	return q.Encode()
+}
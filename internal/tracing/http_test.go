@@ -0,0 +1,136 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"roadmapapi/internal/hive"
+	"roadmapapi/internal/problem"
+	"roadmapapi/internal/tracing"
+)
+
+// TestRequestTrace drives a request through the middleware into a Hive
+// upstream call and checks the exported span tree and the traceparent sent
+// on both sides.
+func TestRequestTrace(t *testing.T) {
+	exp := tracing.NewMemoryExporter()
+	tracer := tracing.NewTracer(exp, 1)
+	tracing.SetDefault(tracer)
+	t.Cleanup(func() { tracing.SetDefault(nil) })
+
+	var upstreamTraceparent string
+	hiveSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results":[],"page":1,"limit":20,"totalPages":1,"totalResults":0}`))
+	}))
+	defer hiveSrv.Close()
+	client := hive.NewClient(hiveSrv.URL, hiveSrv.Client())
+
+	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Get("/hive/{column}", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := client.FetchPage(r.Context(), hive.Query{Column: chi.URLParam(r, "column"), Page: 1}); err != nil {
+			problem.UpstreamError(w, r, "hive", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	const (
+		callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+		callerSpan  = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodGet, "/hive/in-progress?api_key=secret&cache=false", nil)
+	req.Header.Set("traceparent", "00-"+callerTrace+"-"+callerSpan+"-01")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if err := tracer.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exp.Spans()
+	byID := make(map[tracing.SpanID]tracing.SpanData, len(spans))
+	var server, clientSpan *tracing.SpanData
+	for i, s := range spans {
+		byID[s.Context.SpanID] = s
+		if s.Context.TraceID.String() != callerTrace {
+			t.Errorf("span %q has trace %s, want the caller's %s", s.Name, s.Context.TraceID, callerTrace)
+		}
+		switch s.Kind {
+		case tracing.KindServer:
+			server = &spans[i]
+		case tracing.KindClient:
+			clientSpan = &spans[i]
+		}
+	}
+	if server == nil || clientSpan == nil {
+		t.Fatalf("want a server and a client span, got %d spans: %+v", len(spans), spans)
+	}
+
+	if server.Name != "GET /hive/{column}" {
+		t.Errorf("server span name = %q, want the route pattern", server.Name)
+	}
+	if server.Parent.String() != callerSpan {
+		t.Errorf("server span parent = %s, want the caller's span %s", server.Parent, callerSpan)
+	}
+	for _, a := range server.Attrs {
+		if a.Key == "url.query" && a.Value != "cache=false" {
+			t.Errorf("url.query = %q, want the api_key left out", a.Value)
+		}
+	}
+
+	// The client span must descend from the server span.
+	parent, ok := clientSpan.Parent, false
+	for parent.IsValid() {
+		if parent == server.Context.SpanID {
+			ok = true
+			break
+		}
+		p, found := byID[parent]
+		if !found {
+			break
+		}
+		parent = p.Parent
+	}
+	if !ok {
+		t.Errorf("client span %q does not descend from the server span", clientSpan.Name)
+	}
+
+	if want := "00-" + callerTrace + "-" + clientSpan.Context.SpanID.String() + "-01"; upstreamTraceparent != want {
+		t.Errorf("upstream traceparent = %q, want %q", upstreamTraceparent, want)
+	}
+	if want := "00-" + callerTrace + "-" + server.Context.SpanID.String() + "-01"; rec.Header().Get("traceparent") != want {
+		t.Errorf("response traceparent = %q, want %q", rec.Header().Get("traceparent"), want)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"future version with extra fields", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"version 00 with extra fields", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"short span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set("traceparent", tt.value)
+		if got := tracing.Extract(h).IsValid(); got != tt.valid {
+			t.Errorf("%s: Extract(%q).IsValid() = %v, want %v", tt.name, tt.value, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const traceparentHeader = "traceparent"
+
+// Extract parses a W3C traceparent header. Malformed or all-zero values
+// yield an invalid SpanContext so the caller starts a fresh trace.
+func Extract(h http.Header) SpanContext {
+	v := strings.TrimSpace(h.Get(traceparentHeader))
+	parts := strings.Split(v, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}
+	}
+	var sc SpanContext
+	if b, err := hex.DecodeString(parts[1]); err != nil || len(b) != 16 {
+		return SpanContext{}
+	} else {
+		copy(sc.TraceID[:], b)
+	}
+	if b, err := hex.DecodeString(parts[2]); err != nil || len(b) != 8 {
+		return SpanContext{}
+	} else {
+		copy(sc.SpanID[:], b)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return SpanContext{}
+	}
+	sc.Sampled = flags[0]&0x01 == 1
+	sc.Remote = true
+	if !sc.IsValid() {
+		return SpanContext{}
+	}
+	return sc
+}
+
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(traceparentHeader, formatTraceparent(sc))
+}
+
+func formatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
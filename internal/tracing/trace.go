@@ -0,0 +1,265 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+type SpanID [8]byte
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(k, v string) Attr        { return Attr{k, v} }
+func Int(k string, v int) Attr       { return Attr{k, int64(v)} }
+func Int64(k string, v int64) Attr   { return Attr{k, v} }
+func Bool(k string, v bool) Attr     { return Attr{k, v} }
+func Float(k string, v float64) Attr { return Attr{k, v} }
+
+type Event struct {
+	Name  string
+	Time  time.Time
+	Attrs []Attr
+}
+
+// SpanData is the immutable record handed to exporters once a span ends.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	Context       SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attrs         []Attr
+	Events        []Event
+	Status        StatusCode
+	StatusMessage string
+}
+
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+func (s *Span) recording() bool { return s != nil && s.data.Context.Sampled }
+
+func (s *Span) SetName(name string) {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttrs(attrs ...Attr) {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+func (s *Span) AddEvent(name string, attrs ...Attr) {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	s.data.Events = append(s.data.Events, Event{Name: name, Time: time.Now(), Attrs: attrs})
+	s.mu.Unlock()
+}
+
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	s.data.Status = code
+	s.data.StatusMessage = msg
+	s.mu.Unlock()
+}
+
+// RecordError marks the span failed and attaches err as an exception event.
+// A nil err is ignored so callers can pass their return value unconditionally.
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.recording() {
+		return
+	}
+	s.AddEvent("exception", String("exception.message", err.Error()))
+	s.SetStatus(StatusError, err.Error())
+}
+
+func (s *Span) End() {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	d := s.data
+	s.mu.Unlock()
+	s.tracer.processor.onEnd(d)
+}
+
+type Tracer struct {
+	processor   *batchProcessor
+	sampleRatio float64
+}
+
+func NewTracer(exp Exporter, sampleRatio float64) *Tracer {
+	return &Tracer{processor: newBatchProcessor(exp), sampleRatio: sampleRatio}
+}
+
+type spanKey struct{}
+
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attr) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = t.sample(sc.TraceID)
+	}
+	s := &Span{tracer: t, data: SpanData{
+		Name:    name,
+		Kind:    kind,
+		Context: sc,
+		Start:   time.Now(),
+	}}
+	if parent.IsValid() {
+		s.data.Parent = parent.SpanID
+	}
+	if sc.Sampled {
+		s.data.Attrs = append(s.data.Attrs, attrs...)
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (t *Tracer) sample(id TraceID) bool {
+	switch {
+	case t.sampleRatio >= 1:
+		return true
+	case t.sampleRatio <= 0:
+		return false
+	}
+	// Derive the decision from the trace ID so every service sampling at
+	// the same ratio agrees on the same traces.
+	v := binary.BigEndian.Uint64(id[8:]) >> 1
+	return float64(v) < t.sampleRatio*float64(uint64(1)<<63)
+}
+
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.processor.shutdown(ctx)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+type remoteKey struct{}
+
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.data.Context
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+func SetDefault(t *Tracer) { defaultTracer.Store(t) }
+
+// Start begins a span on the default tracer. Without a configured tracer it
+// returns ctx unchanged and a nil span, whose methods are all no-ops.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attrs...)
+}
+
+func StartKind(ctx context.Context, name string, kind SpanKind, attrs ...Attr) (context.Context, *Span) {
+	t := defaultTracer.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.Start(ctx, name, kind, attrs...)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,170 @@
+// Package upstream classifies failures of the roadmap sources so handlers
+// can answer with a meaningful status and a stable error code.
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is what went wrong upstream. Its value is the error code reported to
+// clients.
+type Kind string
+
+const (
+	// KindAuth is a request refused for lack of access.
+	KindAuth Kind = "upstream_auth"
+	// KindRateLimited is a request the upstream asked to retry later.
+	KindRateLimited Kind = "upstream_rate_limited"
+	// KindSchemaChanged is data the source mapping no longer fits.
+	KindSchemaChanged Kind = "upstream_schema_changed"
+	// KindEmpty is a response without any data where some was expected.
+	KindEmpty Kind = "upstream_empty"
+	// KindDecode is a response that could not be decoded.
+	KindDecode Kind = "upstream_decode"
+	// KindTimeout is a request that did not complete in time.
+	KindTimeout Kind = "upstream_timeout"
+	// KindUnavailable is a request that could not be sent or was answered
+	// with a server error.
+	KindUnavailable Kind = "upstream_unavailable"
+	// KindStatus is any other error status.
+	KindStatus Kind = "upstream_status"
+	// KindUnknown is a failure that was not classified.
+	KindUnknown Kind = "upstream_error"
+)
+
+// Error is a failed upstream request. Responses that produce one are never
+// cached.
+type Error struct {
+	Kind     Kind
+	Source   string
+	Endpoint string
+	// Status is the upstream HTTP status, or 0 when there was no response.
+	Status int
+	// RetryAfter is the wait the upstream asked for, if any.
+	RetryAfter time.Duration
+	// Detail describes the failure, such as an excerpt of the response.
+	Detail string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Source, e.Endpoint, e.Kind)
+	if e.Status != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.Status)
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// HTTPStatus is the status to answer a client with.
+func (e *Error) HTTPStatus() int {
+	switch e.Kind {
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindRateLimited:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// As returns the upstream error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// KindOf returns the kind of the upstream error in err's chain, or "".
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return ""
+}
+
+// Response returns the status and error code to answer a failed upstream
+// call with, and the Retry-After to send, if any.
+func Response(err error) (status int, code Kind, retryAfter time.Duration) {
+	e, ok := As(err)
+	if !ok {
+		return http.StatusBadGateway, KindUnknown, 0
+	}
+	return e.HTTPStatus(), e.Kind, e.RetryAfter
+}
+
+// RetryAfterHeader formats d for a Retry-After header, in whole seconds
+// rounded up.
+func RetryAfterHeader(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+// FromStatus classifies an error status; it returns nil below 400.
+func FromStatus(source, endpoint string, resp *http.Response, detail string) *Error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	e := &Error{Kind: KindStatus, Source: source, Endpoint: endpoint, Status: resp.StatusCode, Detail: detail}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.Kind = KindAuth
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = KindRateLimited
+		e.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode == http.StatusGatewayTimeout || resp.StatusCode == http.StatusRequestTimeout:
+		e.Kind = KindTimeout
+	case resp.StatusCode >= 500:
+		e.Kind = KindUnavailable
+		e.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+	}
+	return e
+}
+
+// FromTransport classifies an error from sending a request. Cancellation
+// by the caller is returned as is.
+func FromTransport(source, endpoint string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	e := &Error{Kind: KindUnavailable, Source: source, Endpoint: endpoint, Err: err}
+	var ne net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) {
+		e.Kind = KindTimeout
+	}
+	return e
+}
+
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// Excerpt shortens an upstream body for an error's Detail.
+func Excerpt(b []byte) string {
+	const n = 200
+	if len(b) > n {
+		return strings.ToValidUTF8(string(b[:n]), "") + "..."
+	}
+	return string(b)
+}